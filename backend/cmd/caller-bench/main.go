@@ -0,0 +1,309 @@
+// Command caller-bench load-tests a Caller node. It connects many simulated
+// WebSocket clients spread over several rooms, sends chat messages at a target
+// rate and reports delivery latency, drops, connection failures and the
+// server's memory use.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type options struct {
+	url      string
+	statsURL string
+	clients  int
+	rooms    int
+	rate     float64
+	duration time.Duration
+	ramp     float64
+	drain    time.Duration
+	payload  int
+	jsonOut  bool
+}
+
+// outboxSize bounds the messages a client may have waiting for its writer;
+// ticks that find it full are counted as skipped.
+const outboxSize = 8
+
+type benchClient struct {
+	id     int
+	room   int
+	conn   *websocket.Conn
+	outbox chan struct{}
+}
+
+// run holds the counters shared by all simulated clients.
+type run struct {
+	opts options
+	// start is the UnixNano time sending began, zero before that. The read
+	// loops are already running when it is set.
+	start atomic.Int64
+
+	connected   atomic.Int64
+	connectFail atomic.Int64
+	disconnects atomic.Int64
+	sent        atomic.Int64
+	sendFail    atomic.Int64
+	sendSkipped atomic.Int64
+	expected    atomic.Int64
+	received    atomic.Int64
+	gapMissed   atomic.Int64
+	rateLimited atomic.Int64
+
+	roomSize []atomic.Int64
+	latency  *histogram
+}
+
+func main() {
+	var o options
+	flag.StringVar(&o.url, "url", "ws://localhost:8080/ws", "WebSocket endpoint")
+	flag.StringVar(&o.statsURL, "stats", "", "hub stats endpoint, default derived from -url")
+	flag.IntVar(&o.clients, "clients", 1000, "simulated clients")
+	flag.IntVar(&o.rooms, "rooms", 10, "rooms to spread clients over")
+	flag.Float64Var(&o.rate, "rate", 100, "chat messages per second across all clients")
+	flag.DurationVar(&o.duration, "duration", 30*time.Second, "how long to send messages")
+	flag.Float64Var(&o.ramp, "ramp", 500, "new connections per second")
+	flag.DurationVar(&o.drain, "drain", 3*time.Second, "time to wait for in-flight messages after sending stops")
+	flag.IntVar(&o.payload, "payload", 64, "approximate chat message size in bytes")
+	flag.BoolVar(&o.jsonOut, "json", false, "print the report as JSON")
+	flag.Parse()
+	if o.clients < 1 || o.rooms < 1 || o.rate <= 0 || o.ramp <= 0 {
+		fmt.Fprintln(os.Stderr, "caller-bench: -clients, -rooms, -rate and -ramp must be positive")
+		os.Exit(2)
+	}
+	if o.statsURL == "" {
+		o.statsURL = statsURLFor(o.url)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	r := &run{opts: o, roomSize: make([]atomic.Int64, o.rooms), latency: newHistogram()}
+	rep := r.execute(ctx)
+	if o.jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(rep)
+		return
+	}
+	rep.print(os.Stdout)
+}
+
+func (r *run) execute(ctx context.Context) report {
+	monitor := newServerMonitor(r.opts.statsURL)
+	before := monitor.sample()
+	stopMonitor := monitor.watch(time.Second)
+
+	runID := strconv.FormatInt(time.Now().Unix(), 36)
+	clients := r.connectAll(ctx, runID)
+	r.logf("%d clients connected, %d failed", r.connected.Load(), r.connectFail.Load())
+
+	start := time.Now()
+	r.start.Store(start.UnixNano())
+	sendCtx, stopSending := context.WithTimeout(ctx, r.opts.duration)
+	r.send(sendCtx, clients)
+	stopSending()
+	for _, c := range clients {
+		close(c.outbox)
+	}
+	r.logf("sent %d messages, draining for %s", r.sent.Load(), r.opts.drain)
+	select {
+	case <-time.After(r.opts.drain):
+	case <-ctx.Done():
+	}
+	elapsed := time.Since(start)
+
+	for _, c := range clients {
+		c.conn.Close()
+	}
+	stopMonitor()
+	after := monitor.sample()
+	return r.report(elapsed, before, after, monitor)
+}
+
+func (r *run) logf(format string, args ...any) {
+	if !r.opts.jsonOut {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+func (r *run) connectAll(ctx context.Context, runID string) []*benchClient {
+	var (
+		mu      sync.Mutex
+		clients []*benchClient
+		wg      sync.WaitGroup
+	)
+	interval := time.Duration(float64(time.Second) / r.opts.ramp)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < r.opts.clients; i++ {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			wg.Wait()
+			return clients
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := r.dial(ctx, i, fmt.Sprintf("bench-%s-%d", runID, i%r.opts.rooms))
+			if err != nil {
+				r.connectFail.Add(1)
+				return
+			}
+			r.connected.Add(1)
+			r.roomSize[c.room].Add(1)
+			mu.Lock()
+			clients = append(clients, c)
+			mu.Unlock()
+			go r.readLoop(c)
+			go r.writeLoop(c)
+		}(i)
+	}
+	wg.Wait()
+	return clients
+}
+
+func (r *run) dial(ctx context.Context, i int, room string) (*benchClient, error) {
+	u, err := url.Parse(r.opts.url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("room", room)
+	q.Set("name", "bench-"+strconv.Itoa(i))
+	u.RawQuery = q.Encode()
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(dialCtx, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// the welcome frame comes first
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, _, err := conn.ReadMessage(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	return &benchClient{id: i, room: i % r.opts.rooms, conn: conn, outbox: make(chan struct{}, outboxSize)}, nil
+}
+
+type frame struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Code    string `json:"code"`
+	Missed  int64  `json:"missed"`
+}
+
+func (r *run) readLoop(c *benchClient) {
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			start := r.start.Load()
+			if start == 0 || time.Since(time.Unix(0, start)) < r.opts.duration {
+				r.disconnects.Add(1)
+			}
+			return
+		}
+		var f frame
+		if json.Unmarshal(data, &f) != nil {
+			continue
+		}
+		switch f.Type {
+		case "chat":
+			sentAt, ok := parseStamp(f.Content)
+			if !ok {
+				continue
+			}
+			r.received.Add(1)
+			r.latency.record(time.Since(time.Unix(0, sentAt)))
+		case "gap":
+			r.gapMissed.Add(f.Missed)
+		case "error":
+			if f.Code == "rate_limited" {
+				r.rateLimited.Add(1)
+			}
+		}
+	}
+}
+
+// send picks a random client for every tick and queues a message on its
+// outbox; every other member of its room should receive it.
+func (r *run) send(ctx context.Context, clients []*benchClient) {
+	if len(clients) == 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / r.opts.rate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		c := clients[rand.IntN(len(clients))]
+		select {
+		case c.outbox <- struct{}{}:
+		default:
+			r.sendSkipped.Add(1)
+		}
+	}
+}
+
+// writeLoop is the only writer on a client's connection. It stamps each
+// message as it writes it, so latency doesn't include time spent queued here.
+func (r *run) writeLoop(c *benchClient) {
+	padding := strings.Repeat("x", max(r.opts.payload-40, 0))
+	for range c.outbox {
+		content := fmt.Sprintf("bench:%d:%d:%s", c.id, time.Now().UnixNano(), padding)
+		msg, _ := json.Marshal(map[string]string{"type": "chat", "message": content})
+		if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			r.sendFail.Add(1)
+			continue
+		}
+		r.sent.Add(1)
+		r.expected.Add(r.roomSize[c.room].Load() - 1)
+	}
+}
+
+func parseStamp(content string) (int64, bool) {
+	parts := strings.SplitN(content, ":", 4)
+	if len(parts) < 3 || parts[0] != "bench" {
+		return 0, false
+	}
+	ns, err := strconv.ParseInt(parts[2], 10, 64)
+	return ns, err == nil
+}
+
+func statsURLFor(wsURL string) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "wss":
+		u.Scheme = "https"
+	default:
+		u.Scheme = "http"
+	}
+	u.Path = "/stats/hub"
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// histogram keeps latencies in microsecond buckets that grow by about 5%,
+// which bounds the memory use no matter how many samples arrive.
+type histogram struct {
+	mu      sync.Mutex
+	buckets map[int]int64
+	count   int64
+	max     time.Duration
+}
+
+const bucketGrowth = 1.05
+
+func newHistogram() *histogram {
+	return &histogram{buckets: make(map[int]int64)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	us := max(float64(d.Microseconds()), 1)
+	b := int(math.Log(us) / math.Log(bucketGrowth))
+	h.mu.Lock()
+	h.buckets[b]++
+	h.count++
+	h.max = max(h.max, d)
+	h.mu.Unlock()
+}
+
+func (h *histogram) maximum() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.max
+}
+
+func (h *histogram) percentiles(ps ...float64) []time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]time.Duration, len(ps))
+	if h.count == 0 {
+		return out
+	}
+	keys := make([]int, 0, len(h.buckets))
+	for b := range h.buckets {
+		keys = append(keys, b)
+	}
+	sort.Ints(keys)
+	for i, p := range ps {
+		target := int64(math.Ceil(p / 100 * float64(h.count)))
+		var seen int64
+		for _, b := range keys {
+			seen += h.buckets[b]
+			if seen >= target {
+				out[i] = time.Duration(math.Pow(bucketGrowth, float64(b+1))) * time.Microsecond
+				break
+			}
+		}
+	}
+	return out
+}
+
+type hubStats struct {
+	LocalClients  int `json:"local_clients"`
+	SlowConsumers struct {
+		DroppedOldest int64 `json:"dropped_oldest"`
+		DroppedNewest int64 `json:"dropped_newest"`
+		Disconnected  int64 `json:"disconnected"`
+	} `json:"slow_consumers"`
+	Memory struct {
+		HeapInuse  uint64 `json:"heap_inuse"`
+		Sys        uint64 `json:"sys"`
+		Goroutines int    `json:"goroutines"`
+	} `json:"memory"`
+}
+
+// serverMonitor polls the hub stats endpoint and remembers the peaks.
+type serverMonitor struct {
+	url    string
+	client *http.Client
+
+	mu             sync.Mutex
+	ok             bool
+	peakHeap       uint64
+	peakSys        uint64
+	peakGoroutines int
+}
+
+func newServerMonitor(url string) *serverMonitor {
+	return &serverMonitor{url: url, client: &http.Client{Timeout: 2 * time.Second}}
+}
+
+func (m *serverMonitor) sample() *hubStats {
+	if m.url == "" {
+		return nil
+	}
+	resp, err := m.client.Get(m.url)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	var s hubStats
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&s) != nil {
+		return nil
+	}
+	m.mu.Lock()
+	m.ok = true
+	m.peakHeap = max(m.peakHeap, s.Memory.HeapInuse)
+	m.peakSys = max(m.peakSys, s.Memory.Sys)
+	m.peakGoroutines = max(m.peakGoroutines, s.Memory.Goroutines)
+	m.mu.Unlock()
+	return &s
+}
+
+func (m *serverMonitor) watch(every time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sample()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+type latencyReport struct {
+	P50 string `json:"p50"`
+	P90 string `json:"p90"`
+	P99 string `json:"p99"`
+	Max string `json:"max"`
+}
+
+type serverReport struct {
+	PeakHeapInuse  uint64 `json:"peak_heap_inuse"`
+	PeakSys        uint64 `json:"peak_sys"`
+	PeakGoroutines int    `json:"peak_goroutines"`
+	DroppedOldest  int64  `json:"dropped_oldest"`
+	DroppedNewest  int64  `json:"dropped_newest"`
+	Disconnected   int64  `json:"slow_disconnected"`
+}
+
+type report struct {
+	Clients       int           `json:"clients"`
+	Rooms         int           `json:"rooms"`
+	Connected     int64         `json:"connected"`
+	ConnectFailed int64         `json:"connect_failed"`
+	Disconnected  int64         `json:"disconnected"`
+	Duration      string        `json:"duration"`
+	Sent          int64         `json:"sent"`
+	SendFailed    int64         `json:"send_failed"`
+	SendSkipped   int64         `json:"send_skipped"`
+	RateLimited   int64         `json:"rate_limited"`
+	SendRate      float64       `json:"send_rate"`
+	Expected      int64         `json:"expected_deliveries"`
+	Received      int64         `json:"received"`
+	GapMissed     int64         `json:"gap_missed"`
+	DropRate      float64       `json:"drop_rate"`
+	DeliveryRate  float64       `json:"delivery_rate"`
+	Latency       latencyReport `json:"latency"`
+	Server        *serverReport `json:"server,omitempty"`
+}
+
+func (r *run) report(elapsed time.Duration, before, after *hubStats, m *serverMonitor) report {
+	p := r.latency.percentiles(50, 90, 99)
+	rep := report{
+		Clients:       r.opts.clients,
+		Rooms:         r.opts.rooms,
+		Connected:     r.connected.Load(),
+		ConnectFailed: r.connectFail.Load(),
+		Disconnected:  r.disconnects.Load(),
+		Duration:      elapsed.Round(time.Millisecond).String(),
+		Sent:          r.sent.Load(),
+		SendFailed:    r.sendFail.Load(),
+		SendSkipped:   r.sendSkipped.Load(),
+		RateLimited:   r.rateLimited.Load(),
+		SendRate:      float64(r.sent.Load()) / r.opts.duration.Seconds(),
+		Expected:      r.expected.Load(),
+		Received:      r.received.Load(),
+		GapMissed:     r.gapMissed.Load(),
+		DeliveryRate:  float64(r.received.Load()) / elapsed.Seconds(),
+		Latency: latencyReport{
+			P50: p[0].String(),
+			P90: p[1].String(),
+			P99: p[2].String(),
+			Max: r.latency.maximum().String(),
+		},
+	}
+	if rep.Expected > 0 {
+		rep.DropRate = 1 - float64(rep.Received)/float64(rep.Expected)
+	}
+	if before != nil && after != nil {
+		m.mu.Lock()
+		rep.Server = &serverReport{
+			PeakHeapInuse:  m.peakHeap,
+			PeakSys:        m.peakSys,
+			PeakGoroutines: m.peakGoroutines,
+			DroppedOldest:  after.SlowConsumers.DroppedOldest - before.SlowConsumers.DroppedOldest,
+			DroppedNewest:  after.SlowConsumers.DroppedNewest - before.SlowConsumers.DroppedNewest,
+			Disconnected:   after.SlowConsumers.Disconnected - before.SlowConsumers.Disconnected,
+		}
+		m.mu.Unlock()
+	}
+	return rep
+}
+
+func (rep report) print(w io.Writer) {
+	fmt.Fprintf(w, "clients      %d in %d rooms (%d connected, %d failed, %d dropped mid-run)\n",
+		rep.Clients, rep.Rooms, rep.Connected, rep.ConnectFailed, rep.Disconnected)
+	fmt.Fprintf(w, "sent         %d messages in %s (%.1f/s, %d failed, %d skipped, %d rate limited)\n",
+		rep.Sent, rep.Duration, rep.SendRate, rep.SendFailed, rep.SendSkipped, rep.RateLimited)
+	fmt.Fprintf(w, "delivered    %d of %d expected (%.1f/s), drop rate %.2f%%, gap notices reported %d missed\n",
+		rep.Received, rep.Expected, rep.DeliveryRate, rep.DropRate*100, rep.GapMissed)
+	fmt.Fprintf(w, "latency      p50 %s  p90 %s  p99 %s  max %s\n",
+		rep.Latency.P50, rep.Latency.P90, rep.Latency.P99, rep.Latency.Max)
+	if rep.Server == nil {
+		fmt.Fprintln(w, "server       stats endpoint unavailable")
+		return
+	}
+	fmt.Fprintf(w, "server       peak heap %s, peak sys %s, peak goroutines %d\n",
+		bytesString(rep.Server.PeakHeapInuse), bytesString(rep.Server.PeakSys), rep.Server.PeakGoroutines)
+	fmt.Fprintf(w, "slow clients dropped oldest %d, dropped newest %d, disconnected %d\n",
+		rep.Server.DroppedOldest, rep.Server.DroppedNewest, rep.Server.Disconnected)
+}
+
+func bytesString(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
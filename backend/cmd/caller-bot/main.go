@@ -0,0 +1,90 @@
+// Command caller-bot is an example bot built on the Caller Go SDK. It joins a
+// room, greets people joining the call and answers a few chat commands.
+package main
+
+import (
+	"JanArsMAI/Caller/pkg/caller"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	serverURL := flag.String("url", "ws://localhost:8080/ws", "Caller WebSocket endpoint")
+	room := flag.String("room", "", "room to join")
+	name := flag.String("name", "caller-bot", "display name")
+	flag.Parse()
+
+	bot := caller.New(caller.Options{
+		URL:  *serverURL,
+		Room: *room,
+		Name: *name,
+		OnError: func(err error) {
+			log.Printf("connection problem: %v", err)
+		},
+	})
+
+	bot.OnWelcome(func(w caller.Welcome) {
+		log.Printf("joined room %s as %s", w.RoomID, w.ClientID)
+	})
+	bot.OnRoomEvent("call.participant_joined", func(m caller.Message) {
+		if name, ok := m.Data["name"].(string); ok && name != "" {
+			reply(bot, fmt.Sprintf("Welcome to the call, %s!", name))
+		}
+	})
+	bot.OnServerError(func(err *caller.ServerError) {
+		log.Printf("server error: %v", err)
+	})
+	bot.OnChat(func(m caller.Message) {
+		switch text := strings.TrimSpace(m.Content); {
+		case text == "!ping":
+			reply(bot, "pong")
+		case text == "!time":
+			reply(bot, time.Now().Format(time.RFC1123))
+		case text == "!last":
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				messages, err := bot.History(ctx, 5)
+				if err != nil {
+					log.Printf("history: %v", err)
+					return
+				}
+				for _, msg := range messages {
+					reply(bot, fmt.Sprintf("[%s] %s: %s", msg.Timestamp.Format("15:04"), short(msg.From), msg.Content))
+				}
+			}()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := bot.Connect(ctx)
+	cancel()
+	if err != nil {
+		log.Fatalf("connect: %v", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	bot.Close()
+}
+
+func reply(bot *caller.Client, text string) {
+	if err := bot.Send(text); err != nil {
+		log.Printf("send: %v", err)
+	}
+}
+
+func short(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const helpText = `commands:
+  /who                       list people in the room
+  /stats                     room statistics
+  /history [n]               show the last n messages
+  /older [n]                 show the n messages before those shown last
+  /room                      show the room ID, name and topic
+  /room name|topic|description|avatar [text]
+                             change or, without text, clear it (host)
+  /room persistent|ephemeral keep the room when empty, or not (host)
+  /room public|private       list the room in the directory, or not (host)
+  /rooms [prefix]            browse public rooms, most active first
+  /search [from:<name>] [room:<id>] text
+                             find messages, newest first; "quote" phrases
+  /participants              list call participants
+  /mute <member> [source]    mute a call participant (host)
+  /unmute <member> [source]  unmute a call participant (host)
+  /kick <member>             remove a participant from the call (host)
+  /role <member> <role>      set participant, speaker or viewer (host)
+  /default-role <role>       role for people joining later (host)
+  /capacity <n>              most people in the room, 0 for the server default (host)
+  /knock on|off              make newcomers wait for a host to admit them (host)
+  /lobby                     list people waiting to get in (host)
+  /admit <member>            let someone in from the lobby (host)
+  /deny <member>             turn someone in the lobby away (host)
+  /record start|stop [id]    start or stop recording the call (host)
+  /record list               list the room's finished recordings
+  /quit                      leave
+other /commands (/topic, /roll, /me, bots; /help lists them) run on the server`
+
+// command runs one input line and reports whether the client should keep
+// running.
+func (u *ui) command(line string) bool {
+	if !strings.HasPrefix(line, "/") {
+		if err := u.client.Send(line); err != nil {
+			u.warn("send: %v", err)
+			return true
+		}
+		u.print("%s %s", u.color(u.screen.Escape.Blue, "you:"), line)
+		return true
+	}
+
+	args := strings.Fields(line)
+	switch args[0] {
+	case "/quit", "/exit":
+		return false
+	case "/help":
+		for _, l := range strings.Split(helpText, "\n") {
+			u.info("%s", l)
+		}
+	case "/who":
+		u.who()
+	case "/stats":
+		u.send(map[string]any{"type": "room.stats"})
+	case "/history", "/older":
+		limit := 20
+		if len(args) > 1 {
+			if n, err := strconv.Atoi(args[1]); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		go u.showHistory(args[0] == "/older", limit)
+	case "/room":
+		if len(args) > 1 {
+			_, value, _ := strings.Cut(strings.TrimPrefix(line, "/room"), args[1])
+			u.updateRoom(args[1], strings.TrimSpace(value))
+			break
+		}
+		u.mu.Lock()
+		room, info := u.room, u.roomInfo
+		u.mu.Unlock()
+		u.info("room %s", room)
+		u.showRoom(info)
+	case "/rooms":
+		req := map[string]any{"type": "rooms.list"}
+		if len(args) > 1 {
+			req["query"] = args[1]
+		}
+		u.send(req)
+	case "/search":
+		u.search(strings.TrimSpace(strings.TrimPrefix(line, "/search")))
+	case "/participants":
+		u.send(map[string]any{"type": "call.participants"})
+	case "/mute", "/unmute":
+		m, ok := u.target(args, 2)
+		if !ok {
+			return true
+		}
+		req := map[string]any{"type": "call.mute", "identity": m.ID, "muted": args[0] == "/mute"}
+		if len(args) > 2 {
+			req["source"] = args[2]
+		}
+		u.send(req)
+	case "/kick":
+		if m, ok := u.target(args, 2); ok {
+			u.send(map[string]any{"type": "call.remove", "identity": m.ID})
+		}
+	case "/role":
+		if len(args) < 3 {
+			u.warn("usage: /role <member> <role>")
+			return true
+		}
+		if m, ok := u.target(args, 3); ok {
+			u.send(map[string]any{"type": "call.set_role", "identity": m.ID, "role": args[2]})
+		}
+	case "/default-role":
+		if len(args) < 2 {
+			u.warn("usage: /default-role <role>")
+			return true
+		}
+		u.send(map[string]any{"type": "room.settings", "default_role": args[1]})
+	case "/capacity":
+		n, err := strconv.Atoi(strings.Join(args[1:], ""))
+		if err != nil || n < 0 {
+			u.warn("usage: /capacity <n>")
+			return true
+		}
+		u.send(map[string]any{"type": "room.settings", "max_members": n})
+	case "/knock":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			u.warn("usage: /knock on|off")
+			return true
+		}
+		u.send(map[string]any{"type": "room.settings", "knock": args[1] == "on"})
+	case "/lobby":
+		u.send(map[string]any{"type": "lobby"})
+	case "/admit", "/deny":
+		if len(args) < 2 {
+			u.warn("usage: %s <member>", args[0])
+			return true
+		}
+		m, ok := u.resolveWaiting(args[1])
+		if !ok {
+			u.warn("no single person in the lobby matches %q, see /lobby", args[1])
+			return true
+		}
+		u.send(map[string]any{"type": "lobby" + strings.Replace(args[0], "/", ".", 1), "client_id": m.ID})
+	case "/record":
+		if len(args) < 2 || (args[1] != "start" && args[1] != "stop" && args[1] != "list") {
+			u.warn("usage: /record start|stop|list [egress id]")
+			return true
+		}
+		req := map[string]any{"type": "recording." + args[1]}
+		if len(args) > 2 {
+			req["egress_id"] = args[2]
+		}
+		u.send(req)
+	default:
+		if err := u.client.Send(line); err != nil {
+			u.warn("send: %v", err)
+		}
+	}
+	return true
+}
+
+func (u *ui) updateRoom(field, value string) {
+	switch field {
+	case "persistent", "ephemeral":
+		u.send(map[string]any{"type": "room.lifecycle", "lifecycle": field})
+		return
+	case "public", "private":
+		u.send(map[string]any{"type": "room.update", "public": field == "public"})
+		return
+	}
+	key := map[string]string{"name": "name", "topic": "topic", "description": "description", "avatar": "avatar_url"}[field]
+	if key == "" {
+		u.warn("usage: /room name|topic|description|avatar [text], /room persistent|ephemeral or /room public|private")
+		return
+	}
+	u.send(map[string]any{"type": "room.update", key: value})
+}
+
+func (u *ui) send(req map[string]any) {
+	if err := u.client.SendJSON(req); err != nil {
+		u.warn("send: %v", err)
+	}
+}
+
+// target resolves the member named in args[1].
+func (u *ui) target(args []string, minArgs int) (member, bool) {
+	if len(args) < minArgs {
+		u.warn("usage: %s <member>", args[0])
+		return member{}, false
+	}
+	m, ok := u.resolve(args[1])
+	if !ok {
+		u.warn("no single member matches %q, see /who", args[1])
+	}
+	return m, ok
+}
+
+// search sends a newest-first search; from: and room: words are filters, the
+// rest is passed on as query text so quoted phrases survive. from: takes a
+// sender name; a prefix of a present member's name is completed.
+func (u *ui) search(text string) {
+	req := map[string]any{"type": "search", "sort": "newest"}
+	var rooms, words []string
+	for _, word := range strings.Split(text, " ") {
+		if name, ok := strings.CutPrefix(word, "from:"); ok && name != "" {
+			if m, ok := u.resolve(name); ok {
+				name = m.Name
+			}
+			req["from"] = name
+			continue
+		}
+		if room, ok := strings.CutPrefix(word, "room:"); ok && room != "" {
+			rooms = append(rooms, room)
+			continue
+		}
+		words = append(words, word)
+	}
+	req["query"] = strings.TrimSpace(strings.Join(words, " "))
+	if len(rooms) > 0 {
+		req["rooms"] = rooms
+	}
+	if req["query"] == "" && req["from"] == nil {
+		u.warn("usage: /search [from:<name>] [room:<id>] text")
+		return
+	}
+	u.send(req)
+}
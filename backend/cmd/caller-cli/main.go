@@ -0,0 +1,75 @@
+// Command caller-cli is a terminal client for Caller. It joins a room (or
+// creates one when -room is empty), shows history and presence and lets you
+// chat and moderate with slash-commands.
+package main
+
+import (
+	"JanArsMAI/Caller/pkg/caller"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+func main() {
+	serverURL := flag.String("url", "ws://localhost:8080/ws", "Caller WebSocket endpoint")
+	room := flag.String("room", "", "room to join; empty creates a new room")
+	name := flag.String("name", os.Getenv("USER"), "display name")
+	history := flag.Int("history", 20, "messages of history to show after joining")
+	flag.Parse()
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "caller-cli:", err)
+			os.Exit(1)
+		}
+		defer term.Restore(int(os.Stdin.Fd()), state)
+	}
+	screen := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "> ")
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		screen.SetSize(w, h)
+	}
+
+	ui := newUI(screen, *history)
+	c := caller.New(caller.Options{
+		URL:  *serverURL,
+		Room: *room,
+		Name: *name,
+		OnError: func(err error) {
+			ui.warn("connection: %v, reconnecting...", err)
+		},
+	})
+	ui.attach(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err := c.Connect(ctx)
+	cancel()
+	if err != nil {
+		ui.warn("could not connect to %s: %v", *serverURL, err)
+		return
+	}
+	defer c.Close()
+
+	for {
+		line, err := screen.ReadLine()
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !ui.command(line) {
+			return
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"JanArsMAI/Caller/pkg/caller"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+type member struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type ui struct {
+	screen  *term.Terminal
+	client  *caller.Client
+	history int
+
+	mu      sync.Mutex
+	me      string
+	room    string
+	members map[string]member
+	waiting map[string]member
+	oldest  time.Time
+
+	roomInfo caller.RoomInfo
+}
+
+func newUI(screen *term.Terminal, history int) *ui {
+	return &ui{screen: screen, history: history, members: make(map[string]member), waiting: make(map[string]member)}
+}
+
+func (u *ui) color(c []byte, format string, args ...any) string {
+	return string(c) + fmt.Sprintf(format, args...) + string(u.screen.Escape.Reset)
+}
+
+func (u *ui) print(format string, args ...any) {
+	fmt.Fprintf(u.screen, format+"\n", args...)
+}
+
+func (u *ui) info(format string, args ...any) {
+	u.print("%s", u.color(u.screen.Escape.Cyan, "* "+format, args...))
+}
+
+func (u *ui) warn(format string, args ...any) {
+	u.print("%s", u.color(u.screen.Escape.Red, "! "+format, args...))
+}
+
+func (u *ui) attach(c *caller.Client) {
+	u.client = c
+	c.OnWelcome(func(w caller.Welcome) {
+		u.mu.Lock()
+		first := u.room == ""
+		u.me, u.room, u.roomInfo = w.ClientID, w.RoomID, w.Room
+		u.members = make(map[string]member)
+		u.waiting = make(map[string]member)
+		u.mu.Unlock()
+		if first {
+			u.info("joined room %s — share this ID to invite others", w.RoomID)
+			u.showRoom(w.Room)
+			u.info("type /help for commands")
+		} else {
+			u.info("reconnected to room %s", w.RoomID)
+		}
+		c.SendJSON(map[string]string{"type": "presence"})
+		if first && u.history > 0 {
+			go u.showHistory(false, u.history)
+		}
+	})
+	c.OnChat(func(m caller.Message) {
+		u.printMessage(m)
+	})
+	c.On("presence", func(ev caller.Event) {
+		var p struct {
+			Members []member `json:"members"`
+		}
+		if ev.Decode(&p) != nil {
+			return
+		}
+		u.mu.Lock()
+		for _, m := range p.Members {
+			u.members[m.ID] = m
+		}
+		u.mu.Unlock()
+	})
+	c.OnRoomEvent("presence.joined", func(m caller.Message) {
+		mem := member{ID: str(m.Data["id"]), Name: str(m.Data["name"]), Role: str(m.Data["role"])}
+		u.mu.Lock()
+		u.members[mem.ID] = mem
+		delete(u.waiting, mem.ID)
+		self := mem.ID == u.me
+		u.mu.Unlock()
+		if !self {
+			u.info("%s joined", mem.Name)
+		}
+	})
+	c.OnRoomEvent("presence.left", func(m caller.Message) {
+		id := str(m.Data["id"])
+		u.mu.Lock()
+		delete(u.members, id)
+		u.mu.Unlock()
+		u.info("%s left", str(m.Data["name"]))
+	})
+	c.OnRoomEvent("room.role_changed", func(m caller.Message) {
+		id, role := str(m.Data["identity"]), str(m.Data["role"])
+		u.mu.Lock()
+		mem := u.members[id]
+		mem.Role = role
+		u.members[id] = mem
+		u.mu.Unlock()
+		u.info("%s is now %s", u.nameOf(id), role)
+	})
+	c.OnRoomEvent("room.updated", func(m caller.Message) {
+		var d struct {
+			Room caller.RoomInfo `json:"room"`
+		}
+		raw, _ := json.Marshal(m.Data)
+		if json.Unmarshal(raw, &d) != nil {
+			return
+		}
+		u.mu.Lock()
+		u.roomInfo = d.Room
+		u.mu.Unlock()
+		u.info("%s updated the room", u.nameOf(str(m.Data["by"])))
+		u.showRoom(d.Room)
+	})
+	c.OnRoomEvent("room.lifecycle", func(m caller.Message) {
+		if str(m.Data["lifecycle"]) == "persistent" {
+			u.info("the room is now persistent and stays when everyone leaves")
+		} else {
+			u.info("the room is now ephemeral and closes when everyone leaves")
+		}
+	})
+	c.On("lobby.waiting", func(ev caller.Event) {
+		var w struct {
+			Position int64 `json:"position"`
+		}
+		if ev.Decode(&w) == nil {
+			u.info("the room is full or needs a host's approval, you are number %d in the lobby", w.Position)
+		}
+	})
+	c.On("lobby.admitted", func(caller.Event) {
+		u.info("you were let into the room")
+		c.SendJSON(map[string]string{"type": "presence"})
+	})
+	c.On("lobby.denied", func(caller.Event) {
+		u.warn("a host turned you away from the room")
+	})
+	c.OnRoomEvent("lobby.knock", func(m caller.Message) {
+		mem := member{ID: str(m.Data["id"]), Name: str(m.Data["name"])}
+		u.mu.Lock()
+		u.waiting[mem.ID] = mem
+		u.mu.Unlock()
+		u.info("%s is waiting in the lobby, /admit or /deny them", mem.Name)
+	})
+	c.OnRoomEvent("lobby.decided", func(m caller.Message) {
+		id := str(m.Data["id"])
+		u.mu.Lock()
+		mem := u.waiting[id]
+		delete(u.waiting, id)
+		u.mu.Unlock()
+		if m.Data["admitted"] != true {
+			u.info("%s turned %s away", u.nameOf(str(m.Data["by"])), mem.Name)
+		}
+	})
+	c.On("lobby", func(ev caller.Event) {
+		var l struct {
+			Waiting []struct {
+				member
+				Position int       `json:"position"`
+				Since    time.Time `json:"since"`
+				Admitted bool      `json:"admitted"`
+			} `json:"waiting"`
+		}
+		if ev.Decode(&l) != nil {
+			return
+		}
+		u.mu.Lock()
+		u.waiting = make(map[string]member, len(l.Waiting))
+		for _, w := range l.Waiting {
+			u.waiting[w.ID] = w.member
+		}
+		u.mu.Unlock()
+		if len(l.Waiting) == 0 {
+			u.info("nobody is waiting")
+		}
+		for _, w := range l.Waiting {
+			state := "waiting since " + w.Since.Local().Format("15:04")
+			if w.Admitted {
+				state = "admitted"
+			}
+			u.info("%d. %s [%s] %s", w.Position, w.Name, w.ID[:8], state)
+		}
+	})
+	c.On("recording.list", func(ev caller.Event) {
+		var l struct {
+			Recordings []struct {
+				EgressID  string    `json:"egress_id"`
+				StartedAt time.Time `json:"started_at"`
+				Location  string    `json:"location"`
+				Duration  int64     `json:"duration"`
+			} `json:"recordings"`
+		}
+		if ev.Decode(&l) != nil {
+			return
+		}
+		if len(l.Recordings) == 0 {
+			u.info("no finished recordings")
+		}
+		for _, r := range l.Recordings {
+			u.info("%s  %s  %s  %s", r.StartedAt.Local().Format(time.DateTime), time.Duration(r.Duration).Round(time.Second), r.Location, r.EgressID)
+		}
+	})
+	c.On("search.results", func(ev caller.Event) {
+		var res struct {
+			Query   string `json:"query"`
+			Total   int    `json:"total"`
+			Results []struct {
+				RoomID    string    `json:"room_id"`
+				From      string    `json:"from"`
+				Name      string    `json:"name"`
+				Content   string    `json:"content"`
+				Timestamp time.Time `json:"timestamp"`
+			} `json:"results"`
+		}
+		if ev.Decode(&res) != nil {
+			return
+		}
+		u.mu.Lock()
+		room := u.room
+		u.mu.Unlock()
+		u.info("%d found, showing %d:", res.Total, len(res.Results))
+		for _, r := range res.Results {
+			where := ""
+			if r.RoomID != room {
+				where = " in " + r.RoomID
+			}
+			name := r.Name
+			if name == "" {
+				name = u.nameOf(r.From)
+			}
+			u.info("%s%s %s: %s", r.Timestamp.Local().Format(time.DateTime), where, name, r.Content)
+		}
+	})
+	c.On("rooms.list", func(ev caller.Event) {
+		var l struct {
+			Rooms []struct {
+				RoomID       string    `json:"room_id"`
+				Name         string    `json:"name"`
+				Topic        string    `json:"topic"`
+				Members      int64     `json:"members"`
+				LastActivity time.Time `json:"last_activity"`
+			} `json:"rooms"`
+			Truncated bool `json:"truncated"`
+		}
+		if ev.Decode(&l) != nil {
+			return
+		}
+		if len(l.Rooms) == 0 {
+			u.info("no public rooms found")
+		}
+		for _, r := range l.Rooms {
+			name := r.Name
+			if name == "" {
+				name = "(unnamed)"
+			}
+			u.info("%s  %s  %d online, active %s", r.RoomID, name, r.Members, r.LastActivity.Local().Format(time.DateTime))
+			if r.Topic != "" {
+				u.info("    %s", r.Topic)
+			}
+		}
+		if l.Truncated {
+			u.info("too many rooms match to rank them all, try a longer name")
+		}
+	})
+	c.On("room.stats", func(ev caller.Event) {
+		var s struct {
+			Stats struct {
+				Clients    int64     `json:"clients_count"`
+				CreatedAt  time.Time `json:"created_at"`
+				LastSeen   time.Time `json:"last_seen"`
+				CallActive bool      `json:"call_active"`
+				Lifecycle  string    `json:"lifecycle"`
+			} `json:"stats"`
+		}
+		if ev.Decode(&s) == nil {
+			u.info("clients: %d, created: %s, last activity: %s, call active: %t, %s",
+				s.Stats.Clients, s.Stats.CreatedAt.Format(time.DateTime), s.Stats.LastSeen.Format(time.DateTime), s.Stats.CallActive, s.Stats.Lifecycle)
+		}
+	})
+	c.On("call.participants", func(ev caller.Event) {
+		var p struct {
+			Participants []struct {
+				Identity string `json:"identity"`
+				Name     string `json:"name"`
+				Tracks   []struct {
+					Source string `json:"source"`
+					Muted  bool   `json:"muted"`
+				} `json:"tracks"`
+			} `json:"participants"`
+		}
+		if ev.Decode(&p) != nil {
+			return
+		}
+		if len(p.Participants) == 0 {
+			u.info("nobody is in the call")
+		}
+		for _, part := range p.Participants {
+			tracks := make([]string, 0, len(part.Tracks))
+			for _, t := range part.Tracks {
+				if t.Muted {
+					tracks = append(tracks, strings.ToLower(t.Source)+" (muted)")
+				} else {
+					tracks = append(tracks, strings.ToLower(t.Source))
+				}
+			}
+			u.info("%s %s", u.nameOf(part.Identity), strings.Join(tracks, ", "))
+		}
+	})
+	c.OnLiveKitToken(func(t caller.LiveKitToken) {
+		u.mu.Lock()
+		if mem, ok := u.members[t.Identity]; ok {
+			mem.Role = t.Role
+			u.members[t.Identity] = mem
+		}
+		u.mu.Unlock()
+	})
+	c.OnGap(func(g caller.Gap) {
+		u.warn("%d messages were dropped because the terminal fell behind", g.Missed)
+	})
+	c.OnServerError(func(err *caller.ServerError) {
+		u.warn("%s", err.Message)
+	})
+	c.On("", func(ev caller.Event) {
+		if known[ev.Type] {
+			return
+		}
+		var m caller.Message
+		if ev.Decode(&m) == nil {
+			u.info("%s %v", ev.Type, m.Data)
+		}
+	})
+}
+
+var known = map[string]bool{
+	caller.TypeWelcome: true, caller.TypeChat: true, caller.TypeHistory: true,
+	caller.TypeLiveKitToken: true, caller.TypeError: true, caller.TypeGap: true,
+	"presence": true, "presence.joined": true, "presence.left": true,
+	"room.role_changed": true, "room.updated": true, "room.lifecycle": true, "rooms.list": true, "search.results": true, "recording.list": true, "room.stats": true, "call.participants": true,
+	"lobby": true, "lobby.waiting": true, "lobby.admitted": true, "lobby.denied": true, "lobby.knock": true, "lobby.decided": true,
+}
+
+func (u *ui) printMessage(m caller.Message) {
+	ts := m.Timestamp.Local().Format("15:04")
+	if m.From == "system" {
+		u.print("%s %s", ts, u.color(u.screen.Escape.Yellow, "%s", m.Content))
+		return
+	}
+	u.print("%s %s %s", ts, u.color(u.screen.Escape.Green, "%s:", u.nameOf(m.From)), m.Content)
+}
+
+func (u *ui) nameOf(id string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if id == u.me {
+		return "you"
+	}
+	if mem, ok := u.members[id]; ok && mem.Name != "" {
+		return mem.Name
+	}
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// showHistory prints the last limit messages or, with older, the limit
+// messages before those shown last.
+func (u *ui) showHistory(older bool, limit int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var before time.Time
+	if older {
+		u.mu.Lock()
+		before = u.oldest
+		u.mu.Unlock()
+	}
+	messages, err := u.client.HistoryBefore(ctx, before, limit)
+	if err != nil {
+		u.warn("history: %v", err)
+		return
+	}
+	if len(messages) == 0 {
+		if before.IsZero() {
+			u.info("no messages yet")
+		} else {
+			u.info("no older messages")
+		}
+		return
+	}
+	u.mu.Lock()
+	u.oldest = messages[0].Timestamp
+	u.mu.Unlock()
+	if before.IsZero() {
+		u.info("last %d messages:", len(messages))
+	} else {
+		u.info("%d older messages from %s:", len(messages), messages[0].Timestamp.Local().Format(time.DateOnly))
+	}
+	for _, m := range messages {
+		u.printMessage(m)
+	}
+}
+
+// resolve finds a member by name or ID prefix.
+func (u *ui) resolve(query string) (member, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return match(u.members, query)
+}
+
+func (u *ui) resolveWaiting(query string) (member, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return match(u.waiting, query)
+}
+
+func match(members map[string]member, query string) (member, bool) {
+	var found []member
+	for _, m := range members {
+		if strings.EqualFold(m.Name, query) || strings.HasPrefix(m.ID, query) {
+			found = append(found, m)
+		}
+	}
+	if len(found) != 1 {
+		return member{}, false
+	}
+	return found[0], true
+}
+
+func (u *ui) who() {
+	u.mu.Lock()
+	list := make([]member, 0, len(u.members))
+	for _, m := range u.members {
+		list = append(list, m)
+	}
+	me := u.me
+	u.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	u.info("%d in room:", len(list))
+	for _, m := range list {
+		suffix := ""
+		if m.ID == me {
+			suffix = " (you)"
+		}
+		u.info("  %s [%s] %s%s", m.Name, m.ID[:8], m.Role, suffix)
+	}
+}
+
+// showRoom prints the room's name, topic and description, whichever are set.
+func (u *ui) showRoom(info caller.RoomInfo) {
+	if info.Name != "" {
+		u.info("name: %s", info.Name)
+	}
+	if info.Topic != "" {
+		u.info("topic: %s", info.Topic)
+	}
+	if info.Description != "" {
+		u.info("description: %s", info.Description)
+	}
+	if info.AvatarURL != "" {
+		u.info("avatar: %s", info.AvatarURL)
+	}
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
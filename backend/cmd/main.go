@@ -3,7 +3,11 @@ package main
 import (
 	"JanArsMAI/Caller/internal/di"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,11 +19,26 @@ import (
 
 func main() {
 	ctx := context.Background()
-	container, err := di.NewContainer(ctx)
+	container, err := di.NewContainer(ctx, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal("Failed to initialize application:", err)
 	}
 	defer container.Close()
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go container.WatchConfig(watchCtx)
+	if container.Webhooks != nil {
+		go container.Webhooks.Run(watchCtx)
+	}
+	if container.Archiver != nil {
+		go container.Archiver.Run(watchCtx)
+	}
+	if container.Indexer != nil {
+		go container.Indexer.Run(watchCtx)
+	}
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
@@ -28,11 +47,27 @@ func main() {
 			container.Logger.Fatal("Server failed", zap.Error(err))
 		}
 	}()
+	if container.GRPCServer != nil {
+		grpcAddr := fmt.Sprintf("%s:%s", container.Config().GRPCCfg.Host, container.Config().GRPCCfg.Port)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			container.Logger.Fatal("gRPC listen failed", zap.Error(err))
+		}
+		go func() {
+			container.Logger.Info("gRPC server starting", zap.String("addr", grpcAddr))
+			if err := container.GRPCServer.Serve(lis); err != nil {
+				container.Logger.Error("gRPC server failed", zap.Error(err))
+			}
+		}()
+	}
 	<-stop
 	container.Logger.Info("Shutting down gracefully...")
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	container.Hub.Stop()
+	if container.GRPCServer != nil {
+		container.GRPCServer.Stop()
+	}
 
 	if err := container.Server.Stop(ctx); err != nil {
 		container.Logger.Error("Server shutdown error", zap.Error(err))
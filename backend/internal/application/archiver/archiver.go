@@ -0,0 +1,186 @@
+package archiver
+
+import (
+	"JanArsMAI/Caller/internal/config"
+	"JanArsMAI/Caller/internal/infrastructure/archive"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	pollInterval = 500 * time.Millisecond
+	// claimIdle is how long a batch may stay unacknowledged before another
+	// node, or a retry on this one, archives it again.
+	claimIdle = time.Minute
+	// maxRetention keeps nanosecond cutoffs within int64.
+	maxRetention = 100 * 365 * 24 * time.Hour
+)
+
+var ErrInvalidRetention = errors.New("retention must be between 0 and 100 years")
+
+// Archiver copies every chat message from the Redis queue into the archive
+// database and enforces retention. Every node runs one; the queue is shared
+// through Redis.
+type Archiver struct {
+	repo     *redisrepo.RedisRepo
+	store    *archive.Store
+	cfg      atomic.Pointer[config.ArchiveConfig]
+	consumer string
+	Logger   *zap.Logger
+}
+
+func NewArchiver(cfg config.ArchiveConfig, repo *redisrepo.RedisRepo, store *archive.Store, lg *zap.Logger) *Archiver {
+	a := &Archiver{
+		repo:     repo,
+		store:    store,
+		consumer: uuid.New().String(),
+		Logger:   lg,
+	}
+	a.SetConfig(cfg)
+	return a
+}
+
+func (a *Archiver) SetConfig(cfg config.ArchiveConfig) {
+	a.cfg.Store(&cfg)
+}
+
+// Run archives queued messages and purges expired ones until the context is
+// cancelled.
+func (a *Archiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	purge := time.NewTimer(a.cfg.Load().PurgeInterval)
+	defer purge.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.drain(ctx)
+		case <-purge.C:
+			a.purge(ctx)
+			purge.Reset(a.cfg.Load().PurgeInterval)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (a *Archiver) drain(ctx context.Context) {
+	size := a.cfg.Load().BatchSize
+	for {
+		batch, err := a.repo.ClaimArchiveBatch(ctx, a.consumer, size, claimIdle)
+		if err != nil {
+			if ctx.Err() == nil {
+				a.Logger.Error("Failed to read the archive queue", zap.Error(err))
+			}
+			return
+		}
+		if len(batch) == 0 {
+			return
+		}
+		// unacknowledged messages are claimed again after claimIdle
+		if err := a.store.Save(ctx, batch); err != nil {
+			a.Logger.Error("Failed to archive messages", zap.Int("count", len(batch)), zap.Error(err))
+			return
+		}
+		ids := make([]string, len(batch))
+		for i, q := range batch {
+			ids[i] = q.ID
+		}
+		if err := a.repo.AckArchived(ctx, ids...); err != nil {
+			a.Logger.Error("Failed to acknowledge archived messages", zap.Error(err))
+			return
+		}
+		if len(batch) < size {
+			return
+		}
+	}
+}
+
+// purge applies retention to the archive and to the Redis history of active
+// rooms, which persistent rooms would otherwise keep indefinitely.
+func (a *Archiver) purge(ctx context.Context) {
+	cfg := a.cfg.Load()
+	claimed, err := a.repo.ClaimArchivePurge(ctx, cfg.PurgeInterval*9/10)
+	if err != nil || !claimed {
+		if err != nil {
+			a.Logger.Error("Failed to claim the archive purge", zap.Error(err))
+		}
+		return
+	}
+	now := time.Now()
+	purged, err := a.store.Purge(ctx, cfg.Retention, now)
+	if err != nil {
+		a.Logger.Error("Failed to purge the archive", zap.Error(err))
+		return
+	}
+	a.Logger.Info("Archive purged", zap.Int64("messages", purged))
+
+	policies, err := a.store.Policies(ctx)
+	if err != nil {
+		a.Logger.Error("Failed to load retention policies", zap.Error(err))
+		return
+	}
+	rooms, err := a.repo.GetActiveRooms(ctx)
+	if err != nil {
+		a.Logger.Error("Failed to load active rooms", zap.Error(err))
+		return
+	}
+	for _, roomID := range rooms {
+		retention, ok := policies[roomID]
+		if !ok {
+			retention = cfg.Retention
+		}
+		if retention <= 0 {
+			continue
+		}
+		if err := a.repo.TrimHistory(ctx, roomID, now.Add(-retention)); err != nil {
+			a.Logger.Error("Failed to trim room history", zap.String("room", roomID), zap.Error(err))
+		}
+	}
+}
+
+// Messages returns up to limit archived messages of the room sent before the
+// cutoff, newest first.
+func (a *Archiver) Messages(ctx context.Context, roomID string, before time.Time, limit int) ([]*redisrepo.Message, error) {
+	return a.store.Messages(ctx, roomID, before, limit)
+}
+
+// QueueLength returns how many messages wait to be archived.
+func (a *Archiver) QueueLength(ctx context.Context) (int64, error) {
+	return a.repo.ArchiveQueueLength(ctx)
+}
+
+// Retention returns how long the room's messages are kept, 0 meaning
+// forever, and whether that is the room's own policy or the default.
+func (a *Archiver) Retention(ctx context.Context, roomID string) (time.Duration, bool, error) {
+	retention, own, err := a.store.Retention(ctx, roomID)
+	if err != nil || own {
+		return retention, own, err
+	}
+	return a.cfg.Load().Retention, false, nil
+}
+
+// RetentionPolicies returns the default retention and every room-specific
+// one.
+func (a *Archiver) RetentionPolicies(ctx context.Context) (time.Duration, map[string]time.Duration, error) {
+	policies, err := a.store.Policies(ctx)
+	return a.cfg.Load().Retention, policies, err
+}
+
+func (a *Archiver) SetRetention(ctx context.Context, roomID string, retention time.Duration) error {
+	if retention < 0 || retention > maxRetention {
+		return ErrInvalidRetention
+	}
+	return a.store.SetRetention(ctx, roomID, retention)
+}
+
+// ClearRetention returns the room to the default policy.
+func (a *Archiver) ClearRetention(ctx context.Context, roomID string) error {
+	return a.store.ClearRetention(ctx, roomID)
+}
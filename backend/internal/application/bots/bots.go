@@ -0,0 +1,248 @@
+package bots
+
+import (
+	"JanArsMAI/Caller/internal/application/webhooks"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidName     = errors.New("bot name must be 1-32 lowercase letters, digits, '-' or '_'")
+	ErrInvalidURL      = errors.New("bot url must be an absolute http or https url")
+	ErrInvalidCommand  = errors.New("bot commands must be 1-32 lowercase letters, digits, '-' or '_'")
+	ErrReservedCommand = errors.New("command is built into the server")
+	ErrCommandTaken    = errors.New("command is already handled by another bot")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+const (
+	registryRefresh = 10 * time.Second
+	callTimeout     = 5 * time.Second
+	maxReplySize    = 16 << 10
+	maxReplyText    = 4000
+)
+
+// User is the member who ran the command.
+type User struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role,omitempty"`
+}
+
+// Request is the JSON body POSTed to a bot for every command it handles.
+// It is signed like webhook deliveries.
+type Request struct {
+	Command   string    `json:"command"`
+	Args      string    `json:"args"`
+	RoomID    string    `json:"room_id"`
+	User      User      `json:"user"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Reply is what a bot answers with. An empty Text posts nothing; Private
+// shows the text to the caller only instead of posting it to the room.
+type Reply struct {
+	Text    string `json:"text"`
+	Private bool   `json:"private"`
+}
+
+// Registry keeps the externally registered bots and calls them. Bots are
+// stored in Redis so every node sees the same set.
+type Registry struct {
+	repo     *redisrepo.RedisRepo
+	reserved []string
+	http     *http.Client
+	Logger   *zap.Logger
+
+	mu       sync.RWMutex
+	bots     []*redisrepo.Bot
+	loadedAt time.Time
+}
+
+// NewRegistry creates a registry that refuses to register the reserved
+// (built-in) command names.
+func NewRegistry(repo *redisrepo.RedisRepo, reserved []string, lg *zap.Logger) *Registry {
+	return &Registry{
+		repo:     repo,
+		reserved: reserved,
+		http:     &http.Client{Timeout: callTimeout},
+		Logger:   lg,
+	}
+}
+
+// Register validates and stores a bot. A name that is already registered is
+// refused with redisrepo.ErrBotExists unless replace is set. A signing secret
+// is generated when none is given.
+func (r *Registry) Register(ctx context.Context, bot *redisrepo.Bot, replace bool) error {
+	if !namePattern.MatchString(bot.Name) {
+		return ErrInvalidName
+	}
+	u, err := url.Parse(bot.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if len(bot.Commands) == 0 {
+		return ErrInvalidCommand
+	}
+	bots, err := r.repo.GetBots(ctx)
+	if err != nil {
+		return err
+	}
+	if !replace && slices.ContainsFunc(bots, func(other *redisrepo.Bot) bool { return other.Name == bot.Name }) {
+		return redisrepo.ErrBotExists
+	}
+	for _, command := range bot.Commands {
+		if !namePattern.MatchString(command) {
+			return ErrInvalidCommand
+		}
+		if slices.Contains(r.reserved, command) {
+			return fmt.Errorf("%w: /%s", ErrReservedCommand, command)
+		}
+		for _, other := range bots {
+			if other.Name == bot.Name || !slices.Contains(other.Commands, command) {
+				continue
+			}
+			if other.RoomID == "" || bot.RoomID == "" || other.RoomID == bot.RoomID {
+				return fmt.Errorf("%w: /%s", ErrCommandTaken, command)
+			}
+		}
+	}
+	if bot.Secret == "" {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		bot.Secret = hex.EncodeToString(secret)
+	}
+	bot.CreatedAt = time.Now().UTC()
+	if err := r.repo.SaveBot(ctx, bot, replace); err != nil {
+		return err
+	}
+	r.reload(ctx)
+	return nil
+}
+
+func (r *Registry) Unregister(ctx context.Context, name string) error {
+	if err := r.repo.DeleteBot(ctx, name); err != nil {
+		return err
+	}
+	r.reload(ctx)
+	return nil
+}
+
+func (r *Registry) Bots(ctx context.Context) ([]*redisrepo.Bot, error) {
+	return r.repo.GetBots(ctx)
+}
+
+// Lookup returns the bot handling the command in the room. Room-scoped bots
+// win over deployment-wide ones.
+func (r *Registry) Lookup(ctx context.Context, roomID, command string) (*redisrepo.Bot, bool) {
+	var found *redisrepo.Bot
+	for _, bot := range r.cached(ctx) {
+		if !slices.Contains(bot.Commands, command) {
+			continue
+		}
+		if bot.RoomID == roomID {
+			return bot, true
+		}
+		if bot.RoomID == "" {
+			found = bot
+		}
+	}
+	return found, found != nil
+}
+
+// Commands lists the commands available in the room, sorted by name.
+func (r *Registry) Commands(ctx context.Context, roomID string) []string {
+	var commands []string
+	for _, bot := range r.cached(ctx) {
+		if bot.RoomID == "" || bot.RoomID == roomID {
+			commands = append(commands, bot.Commands...)
+		}
+	}
+	slices.Sort(commands)
+	return slices.Compact(commands)
+}
+
+// Call sends the command to the bot and decodes its reply.
+func (r *Registry) Call(ctx context.Context, bot *redisrepo.Bot, req *Request) (*Reply, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, bot.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now().Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Caller-Bots/1")
+	httpReq.Header.Set("X-Caller-Command", req.Command)
+	httpReq.Header.Set("X-Caller-Timestamp", strconv.FormatInt(ts, 10))
+	httpReq.Header.Set("X-Caller-Signature", webhooks.Sign(bot.Secret, ts, body))
+
+	resp, err := r.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxReplySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("bot responded with %s", resp.Status)
+	}
+	reply := &Reply{}
+	if len(bytes.TrimSpace(data)) == 0 || resp.StatusCode == http.StatusNoContent {
+		return reply, nil
+	}
+	if err := json.Unmarshal(data, reply); err != nil {
+		return nil, fmt.Errorf("invalid bot reply: %w", err)
+	}
+	if len(reply.Text) > maxReplyText {
+		reply.Text = strings.ToValidUTF8(reply.Text[:maxReplyText], "")
+	}
+	return reply, nil
+}
+
+// cached returns the bot list, reloading it from Redis when it is older than
+// registryRefresh so registrations made on other nodes show up.
+func (r *Registry) cached(ctx context.Context) []*redisrepo.Bot {
+	r.mu.RLock()
+	bots, fresh := r.bots, time.Since(r.loadedAt) < registryRefresh
+	r.mu.RUnlock()
+	if fresh {
+		return bots
+	}
+	return r.reload(ctx)
+}
+
+func (r *Registry) reload(ctx context.Context) []*redisrepo.Bot {
+	bots, err := r.repo.GetBots(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.Logger.Error("Failed to load bots", zap.Error(err))
+		r.loadedAt = time.Now()
+		return r.bots
+	}
+	r.bots, r.loadedAt = bots, time.Now()
+	return bots
+}
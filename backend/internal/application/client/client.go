@@ -1,40 +1,128 @@
 package client
 
 import (
-	"github.com/gorilla/websocket"
+	"JanArsMAI/Caller/internal/application/wire"
+	"sync"
+
 	"go.uber.org/zap"
 )
 
+type SlowConsumerPolicy string
+
+const (
+	DropOldest SlowConsumerPolicy = "drop_oldest"
+	DropNewest SlowConsumerPolicy = "drop_newest"
+	Disconnect SlowConsumerPolicy = "disconnect"
+)
+
+type DeliveryResult int
+
+const (
+	Delivered DeliveryResult = iota
+	DeliveredAfterGap
+	DroppedOldest
+	DroppedNewest
+	Disconnected
+	Closed
+)
+
+type gapNotice struct {
+	Type   string `json:"type"`
+	RoomID string `json:"room_id"`
+	Missed int    `json:"missed"`
+}
+
 type Client struct {
-	ID        string
-	Conn      *websocket.Conn
-	Send      chan []byte
-	Room      string
-	UserAgent string
-	Logger    *zap.Logger
+	ID   string
+	Name string
+	// Member is the client's stable identity across connections and
+	// MemberToken the secret it presents to keep it; see MemberIdentity.
+	Member      string
+	MemberToken string
+	Transport   Transport
+	Format      wire.Format
+	Send        chan *wire.Frame
+	Room        string
+	UserAgent   string
+	Logger      *zap.Logger
+
+	mu            sync.Mutex
+	closed        bool
+	disconnecting bool
+	missed        int
 }
 
-func (c *Client) ReadPump(broadcast func([]byte, string)) {
-	defer func() {
-		c.Conn.Close()
-	}()
+// Deliver queues the message without blocking, applying the policy when the
+// send queue is full. It is safe to call concurrently with Close.
+func (c *Client) Deliver(message *wire.Frame, policy SlowConsumerPolicy) DeliveryResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.disconnecting {
+		return Closed
+	}
 
-	for {
-		_, message, err := c.Conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				c.Logger.Error("error: %v", zap.Error(err))
+	result := Delivered
+	if c.missed > 0 && cap(c.Send)-len(c.Send) >= 2 {
+		c.Send <- wire.NewFrame(gapNotice{Type: "gap", RoomID: c.Room, Missed: c.missed})
+		c.missed = 0
+		result = DeliveredAfterGap
+	}
+
+	select {
+	case c.Send <- message:
+		return result
+	default:
+	}
+
+	switch policy {
+	case DropOldest:
+		for {
+			select {
+			case <-c.Send:
+			default:
+			}
+			select {
+			case c.Send <- message:
+				return DroppedOldest
+			default:
 			}
-			break
 		}
-		broadcast(message, c.Room)
+	case Disconnect:
+		c.disconnecting = true
+		c.Transport.Close()
+		return Disconnected
+	default:
+		c.missed++
+		return DroppedNewest
+	}
+}
+
+// Close closes the send queue once; WritePump exits after draining it.
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.Send)
+}
+
+// WriteFrame encodes the frame in the client's format and hands it to the
+// transport. Only the goroutine that owns the transport may call it.
+func (c *Client) WriteFrame(frame *wire.Frame) error {
+	data, err := frame.Bytes(c.Format)
+	if err != nil {
+		c.Logger.Error("Failed to encode frame", zap.String("format", c.Format.String()), zap.Error(err))
+		return nil
 	}
+	return c.Transport.Write(c.Format, data)
 }
 
 func (c *Client) WritePump() {
-	for message := range c.Send {
-		err := c.Conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
+	defer c.Transport.Close()
+	for frame := range c.Send {
+		if err := c.WriteFrame(frame); err != nil {
 			break
 		}
 	}
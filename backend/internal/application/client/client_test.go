@@ -0,0 +1,163 @@
+package client
+
+import (
+	"JanArsMAI/Caller/internal/application/wire"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeTransport records whether the client closed it.
+type fakeTransport struct {
+	closed int
+}
+
+func (t *fakeTransport) Write(wire.Format, []byte) error { return nil }
+
+func (t *fakeTransport) Close() error {
+	t.closed++
+	return nil
+}
+
+type queued struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Missed  int    `json:"missed"`
+}
+
+func newTestClient(queue int) (*Client, *fakeTransport) {
+	tr := &fakeTransport{}
+	return &Client{
+		ID:        "client-1",
+		Room:      "room-1",
+		Transport: tr,
+		Format:    wire.JSON,
+		Send:      make(chan *wire.Frame, queue),
+		Logger:    zap.NewNop(),
+	}, tr
+}
+
+func chat(content string) *wire.Frame {
+	return wire.NewFrame(queued{Type: "chat", Content: content})
+}
+
+// drain empties the send queue and returns what was in it.
+func drain(t *testing.T, c *Client) []queued {
+	t.Helper()
+	var frames []queued
+	for {
+		select {
+		case fr := <-c.Send:
+			data, err := fr.Bytes(wire.JSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var q queued
+			if err := json.Unmarshal(data, &q); err != nil {
+				t.Fatal(err)
+			}
+			frames = append(frames, q)
+		default:
+			return frames
+		}
+	}
+}
+
+func contents(frames []queued) []string {
+	var out []string
+	for _, f := range frames {
+		out = append(out, f.Content)
+	}
+	return out
+}
+
+func TestDeliverAppliesSlowConsumerPolicy(t *testing.T) {
+	tests := []struct {
+		policy  SlowConsumerPolicy
+		results []DeliveryResult
+		kept    []string
+		closed  int
+	}{
+		{
+			policy:  DropOldest,
+			results: []DeliveryResult{Delivered, Delivered, Delivered, DroppedOldest, DroppedOldest},
+			kept:    []string{"3", "4", "5"},
+		},
+		{
+			policy:  DropNewest,
+			results: []DeliveryResult{Delivered, Delivered, Delivered, DroppedNewest, DroppedNewest},
+			kept:    []string{"1", "2", "3"},
+		},
+		{
+			policy:  Disconnect,
+			results: []DeliveryResult{Delivered, Delivered, Delivered, Disconnected, Closed},
+			kept:    []string{"1", "2", "3"},
+			closed:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c, tr := newTestClient(3)
+			for i, want := range tt.results {
+				if got := c.Deliver(chat(strconv.Itoa(i+1)), tt.policy); got != want {
+					t.Fatalf("message %d: Deliver = %v, want %v", i+1, got, want)
+				}
+			}
+			got := contents(drain(t, c))
+			if len(got) != len(tt.kept) {
+				t.Fatalf("queue holds %v, want %v", got, tt.kept)
+			}
+			for i := range got {
+				if got[i] != tt.kept[i] {
+					t.Fatalf("queue holds %v, want %v", got, tt.kept)
+				}
+			}
+			if tr.closed != tt.closed {
+				t.Fatalf("transport closed %d times, want %d", tr.closed, tt.closed)
+			}
+		})
+	}
+}
+
+func TestGapFrameReportsDroppedNewest(t *testing.T) {
+	c, _ := newTestClient(3)
+	for _, m := range []string{"1", "2", "3", "4", "5"} {
+		c.Deliver(chat(m), DropNewest)
+	}
+	drain(t, c)
+
+	if got := c.Deliver(chat("6"), DropNewest); got != DeliveredAfterGap {
+		t.Fatalf("Deliver = %v, want DeliveredAfterGap", got)
+	}
+	frames := drain(t, c)
+	if len(frames) != 2 || frames[0].Type != "gap" || frames[0].Missed != 2 || frames[1].Content != "6" {
+		t.Fatalf("queue holds %+v, want a gap of 2 then message 6", frames)
+	}
+	if got := c.Deliver(chat("7"), DropNewest); got != Delivered {
+		t.Fatalf("Deliver = %v, want Delivered once the gap was reported", got)
+	}
+}
+
+func TestGapFrameWaitsForRoomForItAndTheMessage(t *testing.T) {
+	c, _ := newTestClient(3)
+	for _, m := range []string{"1", "2", "3", "4"} {
+		c.Deliver(chat(m), DropNewest)
+	}
+	<-c.Send
+
+	// one free slot takes the message; the gap keeps counting
+	if got := c.Deliver(chat("5"), DropNewest); got != Delivered {
+		t.Fatalf("Deliver = %v, want Delivered", got)
+	}
+	if got := c.Deliver(chat("6"), DropNewest); got != DroppedNewest {
+		t.Fatalf("Deliver = %v, want DroppedNewest", got)
+	}
+	drain(t, c)
+	c.Deliver(chat("7"), DropNewest)
+	frames := drain(t, c)
+	if len(frames) != 2 || frames[0].Type != "gap" || frames[0].Missed != 2 {
+		t.Fatalf("queue holds %+v, want a gap of 2 first", frames)
+	}
+}
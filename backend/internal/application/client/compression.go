@@ -0,0 +1,136 @@
+package client
+
+import (
+	"JanArsMAI/Caller/internal/config"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+type CompressionStats struct {
+	Enabled         bool  `json:"enabled"`
+	Level           int   `json:"level"`
+	MinSize         int   `json:"min_size"`
+	FramesSent      int64 `json:"frames_sent"`
+	FramesSkipped   int64 `json:"frames_skipped"`
+	PayloadBytes    int64 `json:"payload_bytes"`
+	CompressedBytes int64 `json:"compressed_bytes"`
+	BytesSaved      int64 `json:"bytes_saved"`
+}
+
+// Compressor decides per frame whether permessage-deflate is worth it and
+// keeps totals of what it saved. Level and minimum size can change at runtime.
+type Compressor struct {
+	enabled bool
+	level   atomic.Int64
+	minSize atomic.Int64
+
+	framesSent      atomic.Int64
+	framesSkipped   atomic.Int64
+	payloadBytes    atomic.Int64
+	compressedBytes atomic.Int64
+}
+
+func NewCompressor(cfg config.CompressionConfig) *Compressor {
+	c := &Compressor{enabled: cfg.Enabled}
+	c.Set(cfg)
+	return c
+}
+
+// Enabled reports whether the upgrader should offer permessage-deflate.
+func (c *Compressor) Enabled() bool {
+	return c.enabled
+}
+
+// Set applies the level and minimum size; enabling or disabling compression
+// needs a restart because it changes the handshake.
+func (c *Compressor) Set(cfg config.CompressionConfig) {
+	c.level.Store(int64(cfg.Level))
+	c.minSize.Store(int64(cfg.MinSize))
+}
+
+// For returns the compressor when the request offers permessage-deflate and
+// the upgrader is going to accept it, nil otherwise.
+func (c *Compressor) For(r *http.Request) *Compressor {
+	if !c.enabled {
+		return nil
+	}
+	for _, ext := range r.Header.Values("Sec-WebSocket-Extensions") {
+		if strings.Contains(ext, "permessage-deflate") {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *Compressor) Write(conn *websocket.Conn, messageType int, data []byte) error {
+	if len(data) < int(c.minSize.Load()) {
+		conn.EnableWriteCompression(false)
+		c.framesSkipped.Add(1)
+		return conn.WriteMessage(messageType, data)
+	}
+	conn.EnableWriteCompression(true)
+	if err := conn.SetCompressionLevel(int(c.level.Load())); err != nil {
+		return err
+	}
+	counter, counted := conn.NetConn().(*countingConn)
+	var before int64
+	if counted {
+		before = counter.written.Load()
+	}
+	if err := conn.WriteMessage(messageType, data); err != nil {
+		return err
+	}
+	if counted {
+		c.framesSent.Add(1)
+		c.payloadBytes.Add(int64(len(data)))
+		c.compressedBytes.Add(counter.written.Load() - before)
+	}
+	return nil
+}
+
+func (c *Compressor) Stats() CompressionStats {
+	payload, compressed := c.payloadBytes.Load(), c.compressedBytes.Load()
+	return CompressionStats{
+		Enabled:         c.enabled,
+		Level:           int(c.level.Load()),
+		MinSize:         int(c.minSize.Load()),
+		FramesSent:      c.framesSent.Load(),
+		FramesSkipped:   c.framesSkipped.Load(),
+		PayloadBytes:    payload,
+		CompressedBytes: compressed,
+		BytesSaved:      payload - compressed,
+	}
+}
+
+// CountingListener wraps accepted connections so the compressor can measure
+// how many bytes each frame took on the wire.
+func CountingListener(l net.Listener) net.Listener {
+	return countingListener{l}
+}
+
+type countingListener struct {
+	net.Listener
+}
+
+func (l countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn}, nil
+}
+
+type countingConn struct {
+	net.Conn
+	written atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+)
+
+// Bounds of the member tokens clients may present on reconnect.
+const (
+	memberTokenMin = 16
+	memberTokenMax = 128
+)
+
+// MemberIdentity resolves the member token a client presented into its
+// stable member ID, issuing a new token when it gave none. The ID is a hash
+// of the token, so only the token's holder can claim it, and it is what
+// persistent rooms key the roles they keep by.
+func MemberIdentity(token string) (member, issued string) {
+	if len(token) < memberTokenMin || len(token) > memberTokenMax {
+		token = uuid.New().String()
+	}
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16]), token
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"JanArsMAI/Caller/internal/application/wire"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PollTransport collects frames for the long-poll request that is currently
+// waiting. Only one poll per session may run at a time.
+type PollTransport struct {
+	mu       sync.Mutex
+	batch    []json.RawMessage
+	busy     atomic.Bool
+	lastSeen atomic.Int64
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewPollTransport() *PollTransport {
+	t := &PollTransport{done: make(chan struct{})}
+	t.Touch()
+	return t
+}
+
+func (t *PollTransport) Write(_ wire.Format, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batch = append(t.batch, json.RawMessage(data))
+	return nil
+}
+
+// Take returns the collected frames and starts a new batch.
+func (t *PollTransport) Take() []json.RawMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	batch := t.batch
+	t.batch = nil
+	if batch == nil {
+		batch = []json.RawMessage{}
+	}
+	return batch
+}
+
+// Acquire marks a poll as running; it fails when another poll already is.
+func (t *PollTransport) Acquire() bool {
+	return t.busy.CompareAndSwap(false, true)
+}
+
+func (t *PollTransport) Release() {
+	t.Touch()
+	t.busy.Store(false)
+}
+
+func (t *PollTransport) Touch() {
+	t.lastSeen.Store(time.Now().UnixNano())
+}
+
+// Idle reports whether no poll has run or finished for longer than d.
+func (t *PollTransport) Idle(d time.Duration) bool {
+	return !t.busy.Load() && time.Since(time.Unix(0, t.lastSeen.Load())) > d
+}
+
+func (t *PollTransport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
+
+func (t *PollTransport) Done() <-chan struct{} {
+	return t.done
+}
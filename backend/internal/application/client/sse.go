@@ -0,0 +1,50 @@
+package client
+
+import (
+	"JanArsMAI/Caller/internal/application/wire"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// SSETransport streams frames downstream as Server-Sent Events. Upstream
+// messages arrive through separate POST requests.
+type SSETransport struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	done chan struct{}
+	once sync.Once
+}
+
+func NewSSETransport(w http.ResponseWriter) *SSETransport {
+	return &SSETransport{
+		w:    w,
+		rc:   http.NewResponseController(w),
+		done: make(chan struct{}),
+	}
+}
+
+func (t *SSETransport) Write(_ wire.Format, data []byte) error {
+	if _, err := fmt.Fprintf(t.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+// Ping writes a comment line so proxies do not time out an idle stream.
+func (t *SSETransport) Ping() error {
+	if _, err := fmt.Fprint(t.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	return t.rc.Flush()
+}
+
+// Close ends the stream; the goroutine serving it watches Done.
+func (t *SSETransport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
+
+func (t *SSETransport) Done() <-chan struct{} {
+	return t.done
+}
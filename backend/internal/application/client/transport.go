@@ -0,0 +1,50 @@
+package client
+
+import (
+	"JanArsMAI/Caller/internal/application/wire"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// Transport carries encoded frames to the remote side of a client. The hub
+// only sees the Client, so clients on different transports share rooms.
+type Transport interface {
+	Write(format wire.Format, data []byte) error
+	Close() error
+}
+
+type WebSocketTransport struct {
+	Conn *websocket.Conn
+	// Compression is set when the connection negotiated permessage-deflate.
+	Compression *Compressor
+}
+
+func (t *WebSocketTransport) Write(format wire.Format, data []byte) error {
+	if t.Compression != nil {
+		return t.Compression.Write(t.Conn, format.MessageType(), data)
+	}
+	return t.Conn.WriteMessage(format.MessageType(), data)
+}
+
+func (t *WebSocketTransport) Close() error {
+	return t.Conn.Close()
+}
+
+func (t *WebSocketTransport) ReadPump(c *Client, handle func(*Client, []byte), unregister func(*Client)) {
+	defer func() {
+		unregister(c)
+		t.Conn.Close()
+	}()
+
+	for {
+		_, message, err := t.Conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				c.Logger.Error("error: %v", zap.Error(err))
+			}
+			break
+		}
+		handle(c, message)
+	}
+}
@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/config"
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"go.uber.org/zap"
+)
+
+type liveKitModule struct {
+	cfg      *config.LiveKitConfig
+	services *livekitapi.Services
+}
+
+// LiveKit returns the current LiveKit configuration and API clients, both nil
+// when video is disabled.
+func (h *Hub) LiveKit() (*config.LiveKitConfig, *livekitapi.Services) {
+	m := h.liveKit.Load()
+	if m == nil {
+		return nil, nil
+	}
+	return m.cfg, m.services
+}
+
+func (h *Hub) SetLiveKit(cfg *config.LiveKitConfig, services *livekitapi.Services) {
+	if cfg == nil || services == nil {
+		h.liveKit.Store(nil)
+		return
+	}
+	h.liveKit.Store(&liveKitModule{cfg: cfg, services: services})
+}
+
+// HandleLiveKitEvent records a verified LiveKit webhook event in the room
+// metadata and announces it to the chat room as a call.* event.
+func (h *Hub) HandleLiveKitEvent(ctx context.Context, event *livekit.WebhookEvent) error {
+	if info := event.GetEgressInfo(); info != nil {
+		return h.handleEgressEvent(ctx, info)
+	}
+
+	roomID := event.GetRoom().GetName()
+	if roomID == "" {
+		return nil
+	}
+
+	var (
+		eventType string
+		data      map[string]any
+		err       error
+	)
+	switch event.GetEvent() {
+	case "room_started":
+		eventType = "call.started"
+		err = h.redisRepo.StartCall(ctx, roomID)
+
+	case "participant_joined":
+		p := event.GetParticipant()
+		eventType = "call.participant_joined"
+		data = map[string]any{"identity": p.GetIdentity(), "name": p.GetName()}
+		err = h.redisRepo.SetCallParticipant(ctx, roomID, &redisrepo.CallParticipant{
+			Identity: p.GetIdentity(),
+			Name:     p.GetName(),
+			JoinedAt: time.Unix(p.GetJoinedAt(), 0),
+		})
+
+	case "participant_left", "participant_connection_aborted":
+		p := event.GetParticipant()
+		eventType = "call.participant_left"
+		data = map[string]any{"identity": p.GetIdentity()}
+		err = h.redisRepo.RemoveCallParticipant(ctx, roomID, p.GetIdentity())
+
+	case "track_published":
+		p, t := event.GetParticipant(), event.GetTrack()
+		track := redisrepo.CallTrack{
+			SID:    t.GetSid(),
+			Type:   t.GetType().String(),
+			Source: t.GetSource().String(),
+			Muted:  t.GetMuted(),
+		}
+		eventType = "call.track_published"
+		data = map[string]any{"identity": p.GetIdentity(), "track": track}
+		err = h.redisRepo.AddCallTrack(ctx, roomID, p.GetIdentity(), track)
+
+	case "room_finished":
+		eventType = "call.finished"
+		err = h.redisRepo.FinishCall(ctx, roomID)
+
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	h.Logger.Info("LiveKit event", zap.String("event", event.GetEvent()), zap.String("room", roomID))
+	return h.publishEvent(ctx, roomID, eventType, data)
+}
+
+func (h *Hub) publishEvent(ctx context.Context, roomID, eventType string, data map[string]any) error {
+	return h.redisRepo.PublishMessage(ctx, roomID, &redisrepo.Message{
+		Type:      eventType,
+		From:      "system",
+		RoomID:    roomID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+}
+
+func (h *Hub) postSystemMessage(ctx context.Context, roomID, content string, data map[string]any) error {
+	return h.postMessage(ctx, roomID, "system", content, data)
+}
+
+// postMessage stores and publishes a chat message on behalf of the server or
+// a bot.
+func (h *Hub) postMessage(ctx context.Context, roomID, from, content string, data map[string]any) error {
+	msg := &redisrepo.Message{
+		Type:      "chat",
+		From:      from,
+		RoomID:    roomID,
+		Content:   content,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+	if err := h.redisRepo.SaveMessage(ctx, roomID, msg); err != nil {
+		return err
+	}
+	return h.redisRepo.PublishMessage(ctx, roomID, msg)
+}
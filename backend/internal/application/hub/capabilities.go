@@ -0,0 +1,22 @@
+package hub
+
+type Capabilities struct {
+	Chat        bool `json:"chat"`
+	Video       bool `json:"video"`
+	Recording   bool `json:"recording"`
+	History     bool `json:"history"`
+	Attachments bool `json:"attachments"`
+}
+
+// Capabilities describes the features this server supports so clients can
+// hide the parts of the UI that would not work.
+func (h *Hub) Capabilities() Capabilities {
+	_, lk := h.LiveKit()
+	video := lk != nil
+	return Capabilities{
+		Chat:      true,
+		Video:     video,
+		Recording: video,
+		History:   true,
+	}
+}
@@ -0,0 +1,247 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/bots"
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	maxDice        = 20
+	maxDieSides    = 1000
+	botCallTimeout = 10 * time.Second
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(h *Hub, cl *client.Client, args string)
+}
+
+var builtinCommands map[string]command
+
+func init() {
+	builtinCommands = map[string]command{
+		"help":  {"/help", "list the available commands", (*Hub).helpCommand},
+		"who":   {"/who", "list who is in the room", (*Hub).whoCommand},
+		"topic": {"/topic [text]", "show or, as host, set the room topic", (*Hub).topicCommand},
+		"roll":  {"/roll [NdM]", "roll dice, 1d6 by default", (*Hub).rollCommand},
+		"me":    {"/me <action>", "post an action, like /me waves", (*Hub).meCommand},
+	}
+}
+
+// BuiltinCommands returns the names of the commands handled by the server,
+// which bots cannot take over.
+func BuiltinCommands() []string {
+	names := make([]string, 0, len(builtinCommands))
+	for name := range builtinCommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SetBots must be called before Run.
+func (h *Hub) SetBots(registry *bots.Registry) {
+	h.bots = registry
+}
+
+// handleCommand runs a chat message starting with "/" instead of posting it.
+// "//text" escapes the slash and posts "/text".
+func (h *Hub) handleCommand(cl *client.Client, text string) {
+	if strings.HasPrefix(text, "//") {
+		h.queueChat(cl, text[1:], nil)
+		return
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(text, "/"), " ")
+	name = strings.ToLower(name)
+	args = strings.TrimSpace(args)
+
+	if cmd, ok := builtinCommands[name]; ok {
+		cmd.run(h, cl, args)
+		return
+	}
+	if h.bots != nil {
+		if bot, ok := h.bots.Lookup(h.ctx, cl.Room, name); ok {
+			go h.callBot(cl, bot, name, args)
+			return
+		}
+	}
+	h.sendError(cl, "unknown_command", fmt.Sprintf("unknown command /%s, try /help", name))
+}
+
+// reply shows a message to the client only; it is neither stored nor
+// published.
+func (h *Hub) reply(cl *client.Client, from, content string) {
+	h.deliver(cl, wire.NewFrame(&redisrepo.Message{
+		Type:      "chat",
+		From:      from,
+		RoomID:    cl.Room,
+		Content:   content,
+		Data:      map[string]any{"private": true},
+		Timestamp: time.Now(),
+	}))
+}
+
+func (h *Hub) helpCommand(cl *client.Client, _ string) {
+	lines := []string{"Commands:"}
+	for _, name := range BuiltinCommands() {
+		cmd := builtinCommands[name]
+		lines = append(lines, fmt.Sprintf("%s - %s", cmd.usage, cmd.help))
+	}
+	if h.bots != nil {
+		for _, name := range h.bots.Commands(h.ctx, cl.Room) {
+			bot, _ := h.bots.Lookup(h.ctx, cl.Room, name)
+			lines = append(lines, fmt.Sprintf("/%s - provided by bot %s", name, bot.Name))
+		}
+	}
+	lines = append(lines, "//text - post text starting with a slash")
+	h.reply(cl, "system", strings.Join(lines, "\n"))
+}
+
+func (h *Hub) whoCommand(cl *client.Client, _ string) {
+	members, err := h.redisRepo.GetRoomMembers(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load room members", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to load room members")
+		return
+	}
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		if m.Role != "" {
+			names = append(names, fmt.Sprintf("%s (%s)", m.Name, m.Role))
+		} else {
+			names = append(names, m.Name)
+		}
+	}
+	h.reply(cl, "system", fmt.Sprintf("%d in the room: %s", len(names), strings.Join(names, ", ")))
+}
+
+func (h *Hub) topicCommand(cl *client.Client, args string) {
+	if args == "" {
+		if topic := h.RoomInfo(cl.Room).Topic; topic != "" {
+			h.reply(cl, "system", "Topic: "+topic)
+		} else {
+			h.reply(cl, "system", "No topic is set")
+		}
+		return
+	}
+	if _, ok := h.changeRoomInfo(cl, redisrepo.RoomInfoUpdate{Topic: &args}); !ok {
+		return
+	}
+	content := fmt.Sprintf("%s set the topic to: %s", cl.Name, args)
+	if err := h.postSystemMessage(h.ctx, cl.Room, content, map[string]any{"topic": args}); err != nil {
+		h.Logger.Error("Failed to announce room topic", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
+
+func (h *Hub) rollCommand(cl *client.Client, args string) {
+	dice, sides, ok := parseDice(args)
+	if !ok {
+		h.sendError(cl, "invalid_request", fmt.Sprintf("usage: /roll [NdM], up to %dd%d", maxDice, maxDieSides))
+		return
+	}
+	results := make([]int, dice)
+	parts := make([]string, dice)
+	total := 0
+	for i := range results {
+		results[i] = rand.IntN(sides) + 1
+		parts[i] = strconv.Itoa(results[i])
+		total += results[i]
+	}
+	spec := fmt.Sprintf("%dd%d", dice, sides)
+	content := fmt.Sprintf("%s rolled %s: %d", cl.Name, spec, total)
+	if dice > 1 {
+		content = fmt.Sprintf("%s rolled %s: %s = %d", cl.Name, spec, strings.Join(parts, " + "), total)
+	}
+	data := map[string]any{"roll": map[string]any{"dice": spec, "results": results, "total": total, "by": cl.ID}}
+	if err := h.postSystemMessage(h.ctx, cl.Room, content, data); err != nil {
+		h.Logger.Error("Failed to post dice roll", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
+
+// parseDice accepts "", "M", "dM" and "NdM".
+func parseDice(spec string) (dice, sides int, ok bool) {
+	if spec == "" {
+		return 1, 6, true
+	}
+	n, m, found := strings.Cut(strings.ToLower(spec), "d")
+	if !found {
+		n, m = "1", n
+	}
+	if n == "" {
+		n = "1"
+	}
+	dice, err1 := strconv.Atoi(n)
+	sides, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || dice < 1 || dice > maxDice || sides < 2 || sides > maxDieSides {
+		return 0, 0, false
+	}
+	return dice, sides, true
+}
+
+func (h *Hub) meCommand(cl *client.Client, args string) {
+	if args == "" {
+		h.sendError(cl, "invalid_request", "usage: /me <action>")
+		return
+	}
+	content := fmt.Sprintf("%s %s", cl.Name, args)
+	data := map[string]any{"action": true}
+	h.queueChat(cl, content, data)
+	// room delivery skips the sender, who typed "/me ..." rather than this
+	h.deliver(cl, wire.NewFrame(&redisrepo.Message{
+		Type:      "chat",
+		From:      cl.ID,
+		Name:      cl.Name,
+		RoomID:    cl.Room,
+		Content:   content,
+		Data:      data,
+		Timestamp: time.Now(),
+	}))
+}
+
+// callBot runs off the client's read loop so a slow bot only delays its own
+// reply.
+func (h *Hub) callBot(cl *client.Client, bot *redisrepo.Bot, name, args string) {
+	ctx, cancel := context.WithTimeout(h.ctx, botCallTimeout)
+	defer cancel()
+
+	user := bots.User{ID: cl.ID, Name: cl.Name}
+	if role, err := h.redisRepo.GetRole(ctx, cl.Room, cl.ID); err == nil {
+		user.Role = string(role)
+	}
+	reply, err := h.bots.Call(ctx, bot, &bots.Request{
+		Command:   name,
+		Args:      args,
+		RoomID:    cl.Room,
+		User:      user,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		h.Logger.Warn("Bot call failed", zap.String("bot", bot.Name), zap.String("command", name), zap.Error(err))
+		h.sendError(cl, "bot_unavailable", fmt.Sprintf("bot %s did not answer /%s", bot.Name, name))
+		return
+	}
+	if reply.Text == "" {
+		return
+	}
+	from := "bot:" + bot.Name
+	if reply.Private {
+		h.reply(cl, from, reply.Text)
+		return
+	}
+	data := map[string]any{"bot": bot.Name, "command": name, "invoked_by": cl.ID}
+	if err := h.postMessage(ctx, cl.Room, from, reply.Text, data); err != nil {
+		h.Logger.Error("Failed to post bot reply", zap.String("bot", bot.Name), zap.Error(err))
+	}
+}
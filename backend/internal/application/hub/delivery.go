@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"JanArsMAI/Caller/internal/config"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+type SlowConsumerStats struct {
+	Policy        string `json:"policy"`
+	SendQueueSize int    `json:"send_queue_size"`
+	DroppedOldest int64  `json:"dropped_oldest"`
+	DroppedNewest int64  `json:"dropped_newest"`
+	GapNotices    int64  `json:"gap_notices"`
+	Disconnected  int64  `json:"disconnected"`
+}
+
+type deliveryCounters struct {
+	droppedOldest atomic.Int64
+	droppedNewest atomic.Int64
+	gapNotices    atomic.Int64
+	disconnected  atomic.Int64
+}
+
+func (h *Hub) deliver(cl *client.Client, message *wire.Frame) bool {
+	switch cl.Deliver(message, h.policy.Load().(client.SlowConsumerPolicy)) {
+	case client.Delivered:
+		return true
+	case client.DeliveredAfterGap:
+		h.counters.gapNotices.Add(1)
+		return true
+	case client.DroppedOldest:
+		h.counters.droppedOldest.Add(1)
+		h.Logger.Debug("client slow, dropped oldest message", zap.String("id", cl.ID[:8]))
+		return true
+	case client.DroppedNewest:
+		h.counters.droppedNewest.Add(1)
+		h.Logger.Warn("client slow, dropping message", zap.String("id", cl.ID[:8]))
+	case client.Disconnected:
+		h.counters.disconnected.Add(1)
+		h.Logger.Warn("client slow, disconnecting", zap.String("id", cl.ID[:8]))
+	}
+	return false
+}
+
+func (h *Hub) SendQueueSize() int {
+	return int(h.sendQueueSize.Load())
+}
+
+// SetHubConfig applies the delivery settings; a new queue size only affects
+// clients that connect afterwards.
+func (h *Hub) SetHubConfig(cfg config.HubConfig) {
+	h.policy.Store(client.SlowConsumerPolicy(cfg.SlowConsumerPolicy))
+	h.sendQueueSize.Store(int64(cfg.SendQueueSize))
+}
+
+func (h *Hub) SlowConsumerStats() SlowConsumerStats {
+	return SlowConsumerStats{
+		Policy:        string(h.policy.Load().(client.SlowConsumerPolicy)),
+		SendQueueSize: h.SendQueueSize(),
+		DroppedOldest: h.counters.droppedOldest.Load(),
+		DroppedNewest: h.counters.droppedNewest.Load(),
+		GapNotices:    h.counters.gapNotices.Load(),
+		Disconnected:  h.counters.disconnected.Load(),
+	}
+}
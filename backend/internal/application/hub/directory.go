@@ -0,0 +1,58 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// RoomPage is one page of the public room directory. NextOffset is set when
+// more rooms follow. Truncated is set when a name search sorted by activity
+// matched too many rooms to rank them all: only the first 500 by name were
+// ranked, and a longer prefix or sorting by name finds the rest.
+type RoomPage struct {
+	Rooms      []*redisrepo.DirectoryEntry `json:"rooms"`
+	NextOffset int                         `json:"next_offset,omitempty"`
+	Truncated  bool                        `json:"truncated,omitempty"`
+}
+
+func (h *Hub) ListRooms(ctx context.Context, q redisrepo.DirectoryQuery) (*RoomPage, error) {
+	q.Normalize()
+	res, err := h.redisRepo.ListRooms(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	page := &RoomPage{Rooms: res.Rooms, Truncated: res.Truncated}
+	if page.Rooms == nil {
+		page.Rooms = []*redisrepo.DirectoryEntry{}
+	}
+	if res.More {
+		page.NextOffset = q.Offset + q.Limit
+	}
+	return page, nil
+}
+
+func (h *Hub) sendRoomList(cl *client.Client, req clientRequest) {
+	page, err := h.ListRooms(h.ctx, redisrepo.DirectoryQuery{
+		Prefix: req.Query,
+		Sort:   redisrepo.DirectorySort(req.Sort),
+		Offset: req.Offset,
+		Limit:  int(req.Limit),
+	})
+	if err != nil {
+		h.Logger.Error("Failed to list rooms", zap.Error(err))
+		h.sendError(cl, "internal", "failed to list rooms")
+		return
+	}
+	frame := map[string]any{"type": "rooms.list", "rooms": page.Rooms}
+	if page.NextOffset > 0 {
+		frame["next_offset"] = page.NextOffset
+	}
+	if page.Truncated {
+		frame["truncated"] = true
+	}
+	h.deliver(cl, wire.NewFrame(frame))
+}
@@ -0,0 +1,22 @@
+package hub
+
+import (
+	"context"
+)
+
+// EventSink receives room events for systems outside the chat, such as
+// outgoing webhooks. Emit is called from the hub loop and must not block.
+type EventSink interface {
+	Emit(ctx context.Context, eventType, roomID string, data map[string]any)
+}
+
+// SetEventSink must be called before Run.
+func (h *Hub) SetEventSink(sink EventSink) {
+	h.events = sink
+}
+
+func (h *Hub) emit(eventType, roomID string, data map[string]any) {
+	if h.events != nil {
+		h.events.Emit(h.ctx, eventType, roomID, data)
+	}
+}
@@ -0,0 +1,123 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"encoding/json"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"go.uber.org/zap"
+)
+
+func grantForRole(role redisrepo.Role) config.TokenGrant {
+	switch role {
+	case redisrepo.RoleHost:
+		return config.TokenGrant{CanPublish: true, CanPublishData: true, CanSubscribe: true, RoomAdmin: true}
+	case redisrepo.RoleSpeaker:
+		return config.TokenGrant{
+			CanPublish:        true,
+			CanPublishSources: []livekit.TrackSource{livekit.TrackSource_MICROPHONE},
+			CanPublishData:    true,
+			CanSubscribe:      true,
+		}
+	case redisrepo.RoleViewer:
+		return config.TokenGrant{CanSubscribe: true}
+	default:
+		return config.TokenGrant{CanPublish: true, CanPublishData: true, CanSubscribe: true}
+	}
+}
+
+func permissionForRole(role redisrepo.Role) *livekit.ParticipantPermission {
+	grant := grantForRole(role)
+	return &livekit.ParticipantPermission{
+		CanPublish:        grant.CanPublish,
+		CanPublishSources: grant.CanPublishSources,
+		CanPublishData:    grant.CanPublishData,
+		CanSubscribe:      grant.CanSubscribe,
+	}
+}
+
+func (h *Hub) sendLiveKitToken(cl *client.Client) {
+	cfg, _ := h.LiveKit()
+	if cfg == nil {
+		return
+	}
+
+	role, err := h.redisRepo.GetRole(h.ctx, cl.Room, cl.ID)
+	if err != nil {
+		h.Logger.Error("Failed to load client role", zap.String("id", cl.ID[:8]), zap.Error(err))
+		return
+	}
+	grant := grantForRole(role)
+	grant.Name = cl.Name
+	metadata, _ := json.Marshal(map[string]string{"role": string(role), "name": cl.Name})
+	grant.Metadata = string(metadata)
+	grant.Attributes = map[string]string{"role": string(role)}
+
+	token, err := cfg.GenerateToken(cl.Room, cl.ID, grant)
+	if err != nil {
+		h.Logger.Error("Failed to generate LiveKit token: %v", zap.Error(err))
+		return
+	}
+
+	tokenMsg := map[string]any{
+		"type":       "livekit-token",
+		"token":      token,
+		"livekitUrl": cfg.ApiUrl,
+		"room":       cl.Room,
+		"identity":   cl.ID,
+		"role":       role,
+		"expiresAt":  time.Now().Add(cfg.TokenTTL).Unix(),
+	}
+
+	if h.deliver(cl, wire.NewFrame(tokenMsg)) {
+		h.Logger.Info("LiveKit token sent to", zap.String("id", cl.ID[:8]), zap.String("role", string(role)))
+	}
+}
+
+func (h *Hub) updateRoomSettings(cl *client.Client, req clientRequest) {
+	role, err := h.redisRepo.GetRole(h.ctx, cl.Room, cl.ID)
+	if err != nil || role != redisrepo.RoleHost {
+		h.sendError(cl, "forbidden", "only the host can change room settings")
+		return
+	}
+	settings, err := h.redisRepo.GetRoomSettings(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load room settings", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to update room settings")
+		return
+	}
+	if req.DefaultRole != "" {
+		defaultRole, ok := redisrepo.ParseRole(req.DefaultRole)
+		if !ok || defaultRole == redisrepo.RoleHost {
+			h.sendError(cl, "invalid_request", "unknown default role")
+			return
+		}
+		settings.DefaultRole = defaultRole
+	}
+	if req.MaxMembers != nil {
+		if *req.MaxMembers < 0 {
+			h.sendError(cl, "invalid_request", "max_members must not be negative")
+			return
+		}
+		settings.MaxMembers = *req.MaxMembers
+	}
+	if req.Knock != nil {
+		settings.Knock = *req.Knock
+	}
+	if err := h.redisRepo.UpdateRoomSettings(h.ctx, cl.Room, settings); err != nil {
+		h.Logger.Error("Failed to update room settings", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to update room settings")
+		return
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, "room.settings", map[string]any{
+		"default_role": settings.DefaultRole,
+		"max_members":  settings.MaxMembers,
+		"knock":        settings.Knock,
+	}); err != nil {
+		h.Logger.Error("Failed to publish room settings", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
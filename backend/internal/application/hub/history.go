@@ -0,0 +1,73 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Archive is long-term message storage that history falls back to once the
+// Redis history runs out.
+type Archive interface {
+	Messages(ctx context.Context, roomID string, before time.Time, limit int) ([]*redisrepo.Message, error)
+}
+
+// SetArchive must be called before Run.
+func (h *Hub) SetArchive(archive Archive) {
+	h.archive = archive
+}
+
+// History returns up to limit messages of the room sent before the cutoff,
+// or the latest ones when before is zero, oldest first. Pass the oldest
+// timestamp of a page as before to get the page preceding it.
+func (h *Hub) History(ctx context.Context, roomID string, before time.Time, limit int64) ([]*redisrepo.Message, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	var messages []*redisrepo.Message
+	var err error
+	if before.IsZero() {
+		messages, err = h.redisRepo.GetRecentMessages(ctx, roomID, limit)
+	} else {
+		messages, err = h.redisRepo.GetMessagesBefore(ctx, roomID, before, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if h.archive != nil && int64(len(messages)) < limit {
+		cursor := before
+		if len(messages) > 0 {
+			cursor = messages[len(messages)-1].Timestamp
+		} else if cursor.IsZero() {
+			cursor = time.Now()
+		}
+		older, err := h.archive.Messages(ctx, roomID, cursor, int(limit)-len(messages))
+		if err != nil {
+			h.Logger.Error("Failed to load archived history", zap.String("room", roomID), zap.Error(err))
+		}
+		messages = append(messages, older...)
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+func (h *Hub) sendHistory(cl *client.Client, before time.Time, limit int64) {
+	messages, err := h.History(h.ctx, cl.Room, before, limit)
+	if err != nil {
+		h.Logger.Error("Failed to load history", zap.String("room", cl.Room), zap.Error(err))
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":     "history",
+		"room_id":  cl.Room,
+		"messages": messages,
+	}))
+}
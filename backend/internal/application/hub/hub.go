@@ -1,146 +1,179 @@
 package hub
 
 import (
+	"JanArsMAI/Caller/internal/application/bots"
 	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/registry"
+	"JanArsMAI/Caller/internal/application/wire"
 	"JanArsMAI/Caller/internal/config"
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
 
 	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
 	"context"
-	"encoding/json"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 )
 
 type BroadcastMsg struct {
-	Message  []byte
+	Content  string
 	RoomID   string
 	ClientID string
+	Name     string
+	Data     map[string]any
 }
 
 type Hub struct {
-	connections map[string]*client.Client
-	Register    chan *client.Client
-	Unregister  chan *client.Client
-	Broadcast   chan BroadcastMsg
-	LiveKitCfg  *config.LiveKitConfig
-	quit        chan struct{}
-	Logger      *zap.Logger
+	clients    *registry.Registry
+	Register   chan *client.Client
+	Unregister chan *client.Client
+	Broadcast  chan BroadcastMsg
+	quit       chan struct{}
+	Logger     *zap.Logger
 
+	liveKit       atomic.Pointer[liveKitModule]
+	policy        atomic.Value
+	sendQueueSize atomic.Int64
+	counters      deliveryCounters
+	limits        atomic.Pointer[config.LimitsConfig]
+	limiters      sync.Map
+	lobby         sync.Map
+	admitted      chan admission
+	lobbyWake     chan struct{}
+
+	events    EventSink
+	archive   Archive
+	search    Searcher
+	bots      *bots.Registry
 	redisRepo *redisrepo.RedisRepo
+	sub       *redisrepo.RoomSubscription
 	ctx       context.Context
 	cancel    context.CancelFunc
 }
 
-func NewHub(cfg *config.LiveKitConfig, cl *redisrepo.RedisRepo, lg *zap.Logger) *Hub {
+func NewHub(cfg *config.LiveKitConfig, hubCfg config.HubConfig, limits config.LimitsConfig, lk *livekitapi.Services, cl *redisrepo.RedisRepo, lg *zap.Logger) *Hub {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Hub{
-		connections: make(map[string]*client.Client),
-		Register:    make(chan *client.Client),
-		Unregister:  make(chan *client.Client),
-		Broadcast:   make(chan BroadcastMsg, 100),
-		LiveKitCfg:  cfg,
-		quit:        make(chan struct{}),
-		redisRepo:   cl,
-		ctx:         ctx,
-		cancel:      cancel,
-		Logger:      lg,
+	h := &Hub{
+		clients:    registry.NewRegistry(),
+		Register:   make(chan *client.Client),
+		Unregister: make(chan *client.Client),
+		Broadcast:  make(chan BroadcastMsg, 100),
+		quit:       make(chan struct{}),
+		admitted:   make(chan admission),
+		lobbyWake:  make(chan struct{}, 1),
+		redisRepo:  cl,
+		sub:        cl.NewRoomSubscription(ctx),
+		ctx:        ctx,
+		cancel:     cancel,
+		Logger:     lg,
 	}
+	h.SetLiveKit(cfg, lk)
+	h.SetHubConfig(hubCfg)
+	h.SetLimits(limits)
+	return h
 }
 
 func (h *Hub) Run() {
 	go h.listenToRedis()
+	go h.watchLobby()
 
 	for {
 		select {
 		case cl := <-h.Register:
-			info := &redisrepo.ClientInfo{
-				ID:        cl.ID,
-				RoomID:    cl.Room,
-				JoinedAt:  time.Now(),
-				UserAgent: cl.UserAgent,
-			}
-
-			if err := h.redisRepo.AddClient(h.ctx, info); err != nil {
-				h.Logger.Error("Failed to save client to Redis: %v", zap.Error(err))
-			}
-			h.connections[cl.ID] = cl
-			h.Logger.Info("client joined room", zap.String("id", cl.ID), zap.String("room", cl.Room))
-			h.sendLiveKitToken(cl)
+			h.join(cl)
 
 		case cl := <-h.Unregister:
-			if err := h.redisRepo.RemoveClient(h.ctx, cl.ID); err != nil {
+			if _, waiting := h.lobby.LoadAndDelete(cl.ID); waiting {
+				if err := h.redisRepo.LeaveLobby(h.ctx, cl.Room, cl.ID); err != nil {
+					h.Logger.Error("Failed to leave lobby", zap.String("room", cl.Room), zap.Error(err))
+				}
+				h.limiters.Delete(cl.ID)
+				cl.Close()
+				continue
+			}
+			last, removed := h.clients.Remove(cl)
+			if !removed {
+				continue
+			}
+			if last {
+				if err := h.sub.Leave(h.ctx, cl.Room); err != nil {
+					h.Logger.Error("Failed to unsubscribe from room", zap.String("room", cl.Room), zap.Error(err))
+				}
+			}
+			promoted, err := h.redisRepo.RemoveClient(h.ctx, cl.ID)
+			if err != nil {
 				h.Logger.Error("Failed to remove client from Redis: %v", zap.Error(err))
 			}
-			delete(h.connections, cl.ID)
-			close(cl.Send)
+			if promoted != "" {
+				go h.handOverHost(cl.Room, promoted, cl.ID)
+			}
+			h.limiters.Delete(cl.ID)
+			cl.Close()
+			h.announcePresence(cl, "presence.left", "")
+			h.emit("member.left", cl.Room, map[string]any{"id": cl.ID, "name": cl.Name})
 			h.Logger.Info("client left room", zap.String("id", cl.ID), zap.String("room", cl.Room))
+			h.wakeLobby()
 
-		case msg := <-h.Broadcast:
-			var originalMsg map[string]any
-			if err := json.Unmarshal(msg.Message, &originalMsg); err != nil {
-				h.Logger.Error("Failed to parse message: %v", zap.Error(err))
-				continue
-			}
+		case a := <-h.admitted:
+			h.admit(a)
 
-			messageText := ""
-			if text, ok := originalMsg["message"].(string); ok {
-				messageText = text
-			} else if content, ok := originalMsg["content"].(string); ok {
-				messageText = content
-			} else {
-				messageText = string(msg.Message)
-			}
+		case msg := <-h.Broadcast:
 			redisMsg := &redisrepo.Message{
 				Type:      "chat",
 				From:      msg.ClientID,
+				Name:      msg.Name,
 				RoomID:    msg.RoomID,
-				Content:   messageText,
+				Content:   msg.Content,
+				Data:      msg.Data,
 				Timestamp: time.Now(),
 			}
 			if err := h.redisRepo.PublishMessage(h.ctx, msg.RoomID, redisMsg); err != nil {
 				h.Logger.Error("Failed to publish message: %v", zap.Error(err))
 			}
 			_ = h.redisRepo.SaveMessage(h.ctx, msg.RoomID, redisMsg)
+			h.emit("message.posted", msg.RoomID, map[string]any{"message": redisMsg})
 
 		case <-h.quit:
 			h.Logger.Info("Stopping hub...")
 			h.cancel()
 
-			for _, cl := range h.connections {
-				close(cl.Send)
+			for _, cl := range h.clients.All() {
+				cl.Close()
+			}
+			if err := h.sub.Close(); err != nil {
+				h.Logger.Error("Failed to close room subscription", zap.Error(err))
 			}
 			return
 		}
 	}
 }
 func (h *Hub) listenToRedis() {
-	pubsub := h.redisRepo.SubscribeAllRooms(h.ctx)
-	defer pubsub.Close()
-
-	ch := pubsub.Channel()
+	ch := h.sub.Channel()
 
 	for {
 		select {
-		case msg := <-ch:
-			var redisMsg redisrepo.Message
-			if err := json.Unmarshal([]byte(msg.Payload), &redisMsg); err != nil {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			// the payload is relayed as is; only a non-JSON client makes
+			// the frame decode it
+			payload := []byte(msg.Payload)
+			from, err := redisrepo.MessageSender(payload)
+			if err != nil {
 				h.Logger.Error("Failed to parse Redis message: %v", zap.Error(err))
 				continue
 			}
-			for _, cl := range h.connections {
-				if cl.Room == redisMsg.RoomID {
-					if cl.ID == redisMsg.From {
-						continue
-					}
-
-					select {
-					case cl.Send <- []byte(msg.Payload):
-					default:
-						h.Logger.Error("client slow, dropping message", zap.String("id", cl.ID[:8]))
-					}
+			frame := wire.NewJSONFrame(payload, func() any { return &redisrepo.Message{} })
+			for _, cl := range h.clients.Room(h.sub.Room(msg)) {
+				if cl.ID == from {
+					continue
 				}
+				h.deliver(cl, frame)
 			}
 
 		case <-h.ctx.Done():
@@ -149,62 +182,144 @@ func (h *Hub) listenToRedis() {
 	}
 }
 
-func (h *Hub) sendLiveKitToken(cl *client.Client) {
-	if h.LiveKitCfg == nil {
+type clientRequest struct {
+	Type        string    `json:"type"`
+	Limit       int64     `json:"limit"`
+	Before      time.Time `json:"before"`
+	After       time.Time `json:"after"`
+	From        string    `json:"from"`
+	Rooms       []string  `json:"rooms"`
+	DefaultRole string    `json:"default_role"`
+	Identity    string    `json:"identity"`
+	Role        string    `json:"role"`
+	Source      string    `json:"source"`
+	Muted       *bool     `json:"muted"`
+	EgressID    string    `json:"egress_id"`
+	Message     string    `json:"message"`
+	Content     string    `json:"content"`
+	Name        *string   `json:"name"`
+	Topic       *string   `json:"topic"`
+	Description *string   `json:"description"`
+	AvatarURL   *string   `json:"avatar_url"`
+	Public      *bool     `json:"public"`
+	Lifecycle   string    `json:"lifecycle"`
+	Query       string    `json:"query"`
+	Sort        string    `json:"sort"`
+	Offset      int       `json:"offset"`
+	ClientID    string    `json:"client_id"`
+	MaxMembers  *int      `json:"max_members"`
+	Knock       *bool     `json:"knock"`
+}
+
+func (h *Hub) HandleMessage(cl *client.Client, message []byte) {
+	if !h.allow(cl) {
+		h.sendError(cl, "rate_limited", "too many messages, slow down")
 		return
 	}
-
-	token, err := h.LiveKitCfg.GenerateToken(cl.Room, cl.ID)
-	if err != nil {
-		h.Logger.Error("Failed to generate LiveKit token: %v", zap.Error(err))
+	if _, waiting := h.lobby.Load(cl.ID); waiting {
+		h.sendError(cl, "in_lobby", "wait until you are admitted to the room")
 		return
 	}
-
-	tokenMsg := map[string]any{
-		"type":       "livekit-token",
-		"token":      token,
-		"livekitUrl": h.LiveKitCfg.ApiUrl,
-		"room":       cl.Room,
-		"identity":   cl.ID,
+	var req clientRequest
+	if err := wire.Unmarshal(cl.Format, message, &req); err != nil {
+		if cl.Format != wire.JSON {
+			h.sendError(cl, "invalid_request", "malformed message")
+			return
+		}
+		// plain text frames are chat messages
+		h.BroadcastToRoom(cl, string(message))
+		return
 	}
-
-	tokenData, _ := json.Marshal(tokenMsg)
-
-	select {
-	case cl.Send <- tokenData:
-		h.Logger.Info("LiveKit token sent to", zap.String("id", cl.ID[:8]))
+	switch req.Type {
+	case "history":
+		h.sendHistory(cl, req.Before, req.Limit)
+	case "presence":
+		h.sendPresence(cl)
+	case "room.stats":
+		h.sendRoomStats(cl)
+	case "livekit.refresh":
+		h.sendLiveKitToken(cl)
+	case "room.settings":
+		h.updateRoomSettings(cl, req)
+	case "room.update":
+		h.updateRoomInfo(cl, req)
+	case "room.lifecycle":
+		h.updateRoomLifecycle(cl, req)
+	case "rooms.list":
+		h.sendRoomList(cl, req)
+	case "search":
+		h.searchMessages(cl, req)
+	case "lobby":
+		h.sendLobby(cl)
+	case "lobby.admit", "lobby.deny":
+		h.decideLobby(cl, req)
+	case "call.participants", "call.mute", "call.remove", "call.set_role":
+		h.handleCallCommand(cl, req)
+	case "recording.start", "recording.stop":
+		h.handleRecordingCommand(cl, req)
+	case "recording.list":
+		h.sendRecordings(cl)
 	default:
-		h.Logger.Error("Client slow, dropping token", zap.String("id", cl.ID[:8]))
+		content := req.Message
+		if content == "" {
+			content = req.Content
+		}
+		if content == "" {
+			if cl.Format != wire.JSON {
+				h.sendError(cl, "invalid_request", "empty message")
+				return
+			}
+			content = string(message)
+		}
+		h.BroadcastToRoom(cl, content)
 	}
 }
 
-func (h *Hub) BroadcastToRoom(message []byte, roomID string) {
-	var msg map[string]any
-	if err := json.Unmarshal(message, &msg); err == nil {
-		if clientID, ok := msg["from"].(string); ok {
-			select {
-			case h.Broadcast <- BroadcastMsg{
-				RoomID:   roomID,
-				Message:  message,
-				ClientID: clientID,
-			}:
-			default:
-				h.Logger.Warn("Broadcast channel full for room", zap.String("room", roomID[:8]))
-			}
-			return
-		}
+func (h *Hub) sendError(cl *client.Client, code, message string) {
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":    "error",
+		"code":    code,
+		"message": message,
+	}))
+}
+
+// BroadcastToRoom queues a chat message from the client to its room. The
+// sender is always the connection's own ID, never a client-supplied field.
+// Messages starting with "/" are run as commands instead.
+func (h *Hub) BroadcastToRoom(cl *client.Client, content string) {
+	if strings.HasPrefix(content, "/") {
+		h.handleCommand(cl, content)
+		return
 	}
+	h.queueChat(cl, content, nil)
+}
+
+func (h *Hub) queueChat(cl *client.Client, content string, data map[string]any) {
 	select {
 	case h.Broadcast <- BroadcastMsg{
-		RoomID:   roomID,
-		Message:  message,
-		ClientID: "system",
+		RoomID:   cl.Room,
+		Content:  content,
+		ClientID: cl.ID,
+		Name:     cl.Name,
+		Data:     data,
 	}:
 	default:
-		h.Logger.Warn("Broadcast channel full for room", zap.String("room", roomID[:8]))
+		h.Logger.Warn("Broadcast channel full for room", zap.String("room", cl.Room))
+	}
+}
+
+func (h *Hub) UnregisterClient(cl *client.Client) {
+	select {
+	case h.Unregister <- cl:
+	case <-h.ctx.Done():
+		cl.Close()
 	}
 }
 
+func (h *Hub) LocalClientsCount() int {
+	return h.clients.Len()
+}
+
 func (h *Hub) GetRoomClients(ctx context.Context, roomID string) ([]string, error) {
 	return h.redisRepo.GetRoomClients(ctx, roomID)
 }
@@ -213,6 +328,10 @@ func (h *Hub) GetRoomClientsCount(ctx context.Context, roomID string) (int64, er
 	return h.redisRepo.GetRoomClientsCount(ctx, roomID)
 }
 
+func (h *Hub) GetRoomStats(ctx context.Context, roomID string) (*redisrepo.RoomStats, error) {
+	return h.redisRepo.GetRoomStats(ctx, roomID)
+}
+
 func (h *Hub) Stop() {
 	close(h.quit)
 }
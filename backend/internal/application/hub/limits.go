@@ -0,0 +1,36 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// SetLimits applies the per-client message rate to new and connected clients.
+func (h *Hub) SetLimits(cfg config.LimitsConfig) {
+	h.limits.Store(&cfg)
+	limit, burst := rateFor(cfg)
+	h.limiters.Range(func(_, value any) bool {
+		limiter := value.(*rate.Limiter)
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+		return true
+	})
+}
+
+func rateFor(cfg config.LimitsConfig) (rate.Limit, int) {
+	if cfg.MessagesPerSecond <= 0 {
+		return rate.Inf, 0
+	}
+	return rate.Limit(cfg.MessagesPerSecond), cfg.Burst
+}
+
+func (h *Hub) allow(cl *client.Client) bool {
+	value, ok := h.limiters.Load(cl.ID)
+	if !ok {
+		limit, burst := rateFor(*h.limits.Load())
+		value, _ = h.limiters.LoadOrStore(cl.ID, rate.NewLimiter(limit, burst))
+	}
+	return value.(*rate.Limiter).Allow()
+}
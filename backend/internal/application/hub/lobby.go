@@ -0,0 +1,208 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// lobbyInterval is how often a node re-checks the clients it has waiting,
+// which also keeps their lobby tickets alive.
+const lobbyInterval = time.Second
+
+// lobbyEntry is a local client waiting to enter its room.
+type lobbyEntry struct {
+	cl       *client.Client
+	position int64
+}
+
+// admission hands a client the lobby let in back to the hub loop.
+type admission struct {
+	cl      *client.Client
+	created bool
+}
+
+func (h *Hub) maxMembers() int {
+	return h.limits.Load().MaxRoomMembers
+}
+
+func clientInfo(cl *client.Client) *redisrepo.ClientInfo {
+	return &redisrepo.ClientInfo{
+		ID:        cl.ID,
+		Member:    cl.Member,
+		Name:      cl.Name,
+		RoomID:    cl.Room,
+		JoinedAt:  time.Now(),
+		UserAgent: cl.UserAgent,
+	}
+}
+
+// join lets a new client into its room or queues it in the lobby.
+func (h *Hub) join(cl *client.Client) {
+	res, err := h.redisRepo.JoinRoom(h.ctx, clientInfo(cl), h.maxMembers())
+	if err != nil {
+		h.Logger.Error("Failed to save client to Redis: %v", zap.Error(err))
+		h.sendError(cl, "internal", "failed to join the room")
+		h.limiters.Delete(cl.ID)
+		cl.Close()
+		return
+	}
+	if res.Admitted {
+		h.enter(cl, res.Created)
+		return
+	}
+	h.lobby.Store(cl.ID, &lobbyEntry{cl: cl, position: res.Position})
+	h.Logger.Info("client waiting in lobby", zap.String("id", cl.ID), zap.String("room", cl.Room), zap.Int64("position", res.Position))
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":     "lobby.waiting",
+		"room_id":  cl.Room,
+		"position": res.Position,
+	}))
+	if err := h.publishEvent(h.ctx, cl.Room, "lobby.knock", map[string]any{"id": cl.ID, "name": cl.Name, "position": res.Position}); err != nil {
+		h.Logger.Error("Failed to publish lobby knock", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
+
+// enter completes a join once the client holds a place in the room.
+func (h *Hub) enter(cl *client.Client, created bool) {
+	if created {
+		h.emit("room.created", cl.Room, map[string]any{"created_by": cl.ID})
+	}
+	role, err := h.redisRepo.AssignRole(h.ctx, cl.Room, cl.ID, cl.Member)
+	if err != nil {
+		h.Logger.Error("Failed to assign room role", zap.String("id", cl.ID[:8]), zap.Error(err))
+	}
+	if h.clients.Add(cl) {
+		if err := h.sub.Join(h.ctx, cl.Room); err != nil {
+			h.Logger.Error("Failed to subscribe to room", zap.String("room", cl.Room), zap.Error(err))
+		}
+	}
+	h.Logger.Info("client joined room", zap.String("id", cl.ID), zap.String("room", cl.Room))
+	h.announcePresence(cl, "presence.joined", role)
+	h.emit("member.joined", cl.Room, map[string]any{"id": cl.ID, "name": cl.Name, "role": role})
+	h.sendLiveKitToken(cl)
+}
+
+// watchLobby re-checks the lobby every lobbyInterval and whenever a member
+// leaves, off the hub loop so the Redis round trips do not hold it up.
+func (h *Hub) watchLobby() {
+	ticker := time.NewTicker(lobbyInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-h.lobbyWake:
+		case <-h.ctx.Done():
+			return
+		}
+		h.admitWaiting()
+	}
+}
+
+// wakeLobby asks watchLobby for an early check.
+func (h *Hub) wakeLobby() {
+	select {
+	case h.lobbyWake <- struct{}{}:
+	default:
+	}
+}
+
+// admitWaiting re-checks every client waiting on this node: admitted ones
+// are handed to the hub loop to enter, denied ones are disconnected and the
+// rest hear about their new position.
+func (h *Hub) admitWaiting() {
+	h.lobby.Range(func(key, value any) bool {
+		entry := value.(*lobbyEntry)
+		cl := entry.cl
+		res, err := h.redisRepo.AdmitFromLobby(h.ctx, clientInfo(cl), h.maxMembers())
+		if err != nil {
+			h.Logger.Error("Failed to check lobby", zap.String("room", cl.Room), zap.Error(err))
+			return true
+		}
+		switch {
+		case res.Admitted:
+			select {
+			case h.admitted <- admission{cl: cl, created: res.Created}:
+			case <-h.ctx.Done():
+				return false
+			}
+		case res.Position == 0:
+			if _, waiting := h.lobby.LoadAndDelete(key); !waiting {
+				return true
+			}
+			h.Logger.Info("client denied entry", zap.String("id", cl.ID), zap.String("room", cl.Room))
+			h.deliver(cl, wire.NewFrame(map[string]any{"type": "lobby.denied", "room_id": cl.Room}))
+			h.limiters.Delete(cl.ID)
+			cl.Close()
+		case res.Position != entry.position:
+			entry.position = res.Position
+			h.deliver(cl, wire.NewFrame(map[string]any{
+				"type":     "lobby.waiting",
+				"room_id":  cl.Room,
+				"position": res.Position,
+			}))
+		}
+		return true
+	})
+}
+
+// admit lets in a client the lobby admitted, unless it disconnected while
+// it was being checked; then its new place in the room is given up again.
+func (h *Hub) admit(a admission) {
+	cl := a.cl
+	if _, waiting := h.lobby.LoadAndDelete(cl.ID); !waiting {
+		promoted, err := h.redisRepo.RemoveClient(h.ctx, cl.ID)
+		if err != nil {
+			h.Logger.Error("Failed to remove client from Redis: %v", zap.Error(err))
+		}
+		if promoted != "" {
+			go h.handOverHost(cl.Room, promoted, cl.ID)
+		}
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{"type": "lobby.admitted", "room_id": cl.Room}))
+	h.enter(cl, a.created)
+}
+
+func (h *Hub) sendLobby(cl *client.Client) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	entries, err := h.redisRepo.GetLobby(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load lobby", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to load the lobby")
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":    "lobby",
+		"room_id": cl.Room,
+		"waiting": entries,
+	}))
+}
+
+// decideLobby records a host's admit or deny; the node holding the waiting
+// client applies it on its next check.
+func (h *Hub) decideLobby(cl *client.Client, req clientRequest) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	admit := req.Type == "lobby.admit"
+	err := h.redisRepo.DecideLobby(h.ctx, cl.Room, req.ClientID, admit)
+	if errors.Is(err, redisrepo.ErrNotInLobby) {
+		h.sendError(cl, "not_found", err.Error())
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to record lobby decision", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to record the decision")
+		return
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, "lobby.decided", map[string]any{"id": req.ClientID, "admitted": admit, "by": cl.ID}); err != nil {
+		h.Logger.Error("Failed to publish lobby decision", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
@@ -0,0 +1,274 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var keys redisrepo.Keys
+
+type nopTransport struct{}
+
+func (nopTransport) Write(wire.Format, []byte) error { return nil }
+func (nopTransport) Close() error                    { return nil }
+
+func newTestHub(t *testing.T, hooks ...redis.Hook) (*Hub, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	for _, hook := range hooks {
+		db.AddHook(hook)
+	}
+	t.Cleanup(func() { db.Close() })
+	h := NewHub(nil, config.HubConfig{SendQueueSize: 64, SlowConsumerPolicy: "drop_newest"}, config.LimitsConfig{Burst: 100}, nil, redisrepo.NewRedisRepo(db), zap.NewNop())
+	go h.Run()
+	t.Cleanup(h.Stop)
+	return h, mr
+}
+
+func newTestClient(id, room string) *client.Client {
+	return &client.Client{
+		ID:        id,
+		Name:      id,
+		Room:      room,
+		Transport: nopTransport{},
+		Format:    wire.JSON,
+		Send:      make(chan *wire.Frame, 64),
+		Logger:    zap.NewNop(),
+	}
+}
+
+// next returns the next frame of the given type the client was sent.
+func next(t *testing.T, cl *client.Client, frameType string) map[string]any {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case fr, ok := <-cl.Send:
+			if !ok {
+				t.Fatalf("%s was disconnected waiting for %s", cl.ID, frameType)
+			}
+			data, err := fr.Bytes(wire.JSON)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var frame map[string]any
+			if err := json.Unmarshal(data, &frame); err != nil {
+				t.Fatal(err)
+			}
+			if frame["type"] == frameType {
+				return frame
+			}
+		case <-timeout:
+			t.Fatalf("%s got no %s", cl.ID, frameType)
+		}
+	}
+}
+
+// disconnected waits until the hub closes the client's send queue.
+func disconnected(t *testing.T, cl *client.Client) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-cl.Send:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s was not disconnected", cl.ID)
+		}
+	}
+}
+
+// data returns the payload of a room event.
+func data(frame map[string]any) map[string]any {
+	d, _ := frame["data"].(map[string]any)
+	return d
+}
+
+// subscribed waits until the hub listens to the room's channel, which it
+// does once a client has entered the room.
+func subscribed(t *testing.T, mr *miniredis.Miniredis, room string) {
+	t.Helper()
+	channel := keys.RoomChannel(room)
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumSub(channel)[channel] == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("nobody entered %s", room)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func request(h *Hub, cl *client.Client, req map[string]any) {
+	data, _ := json.Marshal(req)
+	h.HandleMessage(cl, data)
+}
+
+// openKnockRoom enters a host into room and turns knock mode on.
+func openKnockRoom(t *testing.T, h *Hub, mr *miniredis.Miniredis, room string) *client.Client {
+	t.Helper()
+	host := newTestClient("host", room)
+	h.Register <- host
+	// room events reach the host once the hub has subscribed to the room
+	subscribed(t, mr, room)
+	request(h, host, map[string]any{"type": "room.settings", "knock": true})
+	if settings := next(t, host, "room.settings"); data(settings)["knock"] != true {
+		t.Fatalf("unexpected settings %v", settings)
+	}
+	return host
+}
+
+func TestKnockingClientsWaitForTheHost(t *testing.T) {
+	h, mr := newTestHub(t)
+	host := openKnockRoom(t, h, mr, "room-1")
+
+	alice := newTestClient("alice", "room-1")
+	h.Register <- alice
+	if waiting := next(t, alice, "lobby.waiting"); waiting["position"] != float64(1) {
+		t.Fatalf("unexpected lobby.waiting %v", waiting)
+	}
+	if knock := next(t, host, "lobby.knock"); data(knock)["id"] != "alice" || data(knock)["name"] != "alice" {
+		t.Fatalf("unexpected lobby.knock %v", knock)
+	}
+	request(h, alice, map[string]any{"type": "chat", "content": "let me in"})
+	if err := next(t, alice, "error"); err["code"] != "in_lobby" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	bob := newTestClient("bob", "room-1")
+	h.Register <- bob
+	if waiting := next(t, bob, "lobby.waiting"); waiting["position"] != float64(2) {
+		t.Fatalf("unexpected lobby.waiting %v", waiting)
+	}
+	request(h, bob, map[string]any{"type": "lobby.admit", "client_id": "bob"})
+	if err := next(t, bob, "error"); err["code"] != "in_lobby" {
+		t.Fatalf("a waiting client decided on the lobby: %v", err)
+	}
+
+	// decisions apply on the waiting client's next lobby check
+	request(h, host, map[string]any{"type": "lobby.admit", "client_id": "alice"})
+	next(t, host, "lobby.decided")
+	h.wakeLobby()
+	next(t, alice, "lobby.admitted")
+	if joined := next(t, host, "presence.joined"); data(joined)["id"] != "alice" || data(joined)["role"] != "participant" {
+		t.Fatalf("unexpected presence.joined %v", joined)
+	}
+
+	request(h, host, map[string]any{"type": "lobby.deny", "client_id": "bob"})
+	next(t, host, "lobby.decided")
+	h.wakeLobby()
+	next(t, bob, "lobby.denied")
+	disconnected(t, bob)
+
+	request(h, host, map[string]any{"type": "lobby"})
+	if lobby := next(t, host, "lobby"); len(lobby["waiting"].([]any)) != 0 {
+		t.Fatalf("the lobby still lists %v", lobby["waiting"])
+	}
+	request(h, host, map[string]any{"type": "lobby.admit", "client_id": "bob"})
+	if err := next(t, host, "error"); err["code"] != "not_found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestJoinIsRefusedWhenRedisFails(t *testing.T) {
+	h, mr := newTestHub(t)
+	mr.SetError("ERR unavailable")
+
+	alice := newTestClient("alice", "room-1")
+	h.Register <- alice
+	if err := next(t, alice, "error"); err["code"] != "internal" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	disconnected(t, alice)
+
+	mr.SetError("")
+	bob := newTestClient("bob", "room-1")
+	h.Register <- bob
+	subscribed(t, mr, "room-1")
+	if n := h.LocalClientsCount(); n != 1 {
+		t.Fatalf("%d local clients, want only bob", n)
+	}
+}
+
+// lobbyGate holds the scripts that check room-1's lobby until it is opened.
+type lobbyGate struct {
+	mu      sync.Mutex
+	armed   bool
+	blocked chan struct{}
+	open    chan struct{}
+}
+
+func (g *lobbyGate) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (g *lobbyGate) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (g *lobbyGate) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if name := cmd.Name(); name == "evalsha" || name == "eval" {
+			for _, arg := range cmd.Args() {
+				if s, ok := arg.(string); ok && strings.HasPrefix(s, keys.RoomLobbyKey("room-1")) {
+					g.wait()
+					break
+				}
+			}
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (g *lobbyGate) wait() {
+	g.mu.Lock()
+	armed := g.armed
+	if armed {
+		g.armed = false
+		close(g.blocked)
+	}
+	g.mu.Unlock()
+	if armed {
+		<-g.open
+	}
+}
+
+func TestLobbyCheckRunsOffTheHubLoop(t *testing.T) {
+	gate := &lobbyGate{blocked: make(chan struct{}), open: make(chan struct{})}
+	h, mr := newTestHub(t, gate)
+	t.Cleanup(func() { close(gate.open) })
+	openKnockRoom(t, h, mr, "room-1")
+	alice := newTestClient("alice", "room-1")
+	h.Register <- alice
+	next(t, alice, "lobby.waiting")
+
+	// the next lobby check stalls in Redis
+	gate.mu.Lock()
+	gate.armed = true
+	gate.mu.Unlock()
+	select {
+	case <-gate.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lobby was not checked")
+	}
+
+	carol := newTestClient("carol", "room-2")
+	select {
+	case h.Register <- carol:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hub loop waited for the lobby check")
+	}
+	subscribed(t, mr, "room-2")
+}
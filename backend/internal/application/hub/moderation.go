@@ -0,0 +1,197 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"go.uber.org/zap"
+)
+
+const liveKitCallTimeout = 5 * time.Second
+
+func (h *Hub) requireRole(cl *client.Client, allowed ...redisrepo.Role) bool {
+	role, err := h.redisRepo.GetRole(h.ctx, cl.Room, cl.ID)
+	if err == nil {
+		for _, r := range allowed {
+			if role == r {
+				return true
+			}
+		}
+	}
+	h.sendError(cl, "forbidden", "not allowed for your room role")
+	return false
+}
+
+func (h *Hub) handleCallCommand(cl *client.Client, req clientRequest) {
+	_, lk := h.LiveKit()
+	if lk == nil {
+		h.sendError(cl, "unavailable", "video calls are not configured")
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, liveKitCallTimeout)
+	defer cancel()
+
+	switch req.Type {
+	case "call.participants":
+		h.sendCallParticipants(ctx, cl, lk.Rooms)
+	case "call.mute":
+		h.muteParticipant(ctx, cl, lk.Rooms, req)
+	case "call.remove":
+		h.removeParticipant(ctx, cl, lk.Rooms, req)
+	case "call.set_role":
+		h.setParticipantRole(ctx, cl, lk.Rooms, req)
+	}
+}
+
+func (h *Hub) sendCallParticipants(ctx context.Context, cl *client.Client, rooms *livekitapi.RoomService) {
+	participants, err := rooms.ListParticipants(ctx, cl.Room)
+	if err != nil && err != livekitapi.ErrNotFound {
+		h.Logger.Error("Failed to list call participants", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to list call participants")
+		return
+	}
+	list := make([]map[string]any, 0, len(participants))
+	for _, p := range participants {
+		tracks := make([]map[string]any, 0, len(p.GetTracks()))
+		for _, t := range p.GetTracks() {
+			tracks = append(tracks, map[string]any{
+				"sid":    t.GetSid(),
+				"source": t.GetSource().String(),
+				"muted":  t.GetMuted(),
+			})
+		}
+		list = append(list, map[string]any{
+			"identity": p.GetIdentity(),
+			"name":     p.GetName(),
+			"state":    p.GetState().String(),
+			"tracks":   tracks,
+		})
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":         "call.participants",
+		"room_id":      cl.Room,
+		"participants": list,
+	}))
+}
+
+func (h *Hub) muteParticipant(ctx context.Context, cl *client.Client, rooms *livekitapi.RoomService, req clientRequest) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	sourceName := req.Source
+	if sourceName == "" {
+		sourceName = "microphone"
+	}
+	source, ok := livekit.TrackSource_value[strings.ToUpper(sourceName)]
+	if !ok {
+		h.sendError(cl, "invalid_request", "unknown track source")
+		return
+	}
+	muted := req.Muted == nil || *req.Muted
+	count, err := rooms.MuteSource(ctx, cl.Room, req.Identity, livekit.TrackSource(source), muted)
+	if err == livekitapi.ErrNotFound {
+		h.sendError(cl, "not_found", "participant is not in the call")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to mute participant", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to mute participant")
+		return
+	}
+	h.Logger.Info("participant muted", zap.String("room", cl.Room), zap.String("identity", req.Identity), zap.Int("tracks", count))
+	if err := h.publishEvent(h.ctx, cl.Room, "call.participant_muted", map[string]any{
+		"identity": req.Identity,
+		"source":   strings.ToLower(sourceName),
+		"muted":    muted,
+		"by":       cl.ID,
+	}); err != nil {
+		h.Logger.Error("Failed to publish mute event", zap.Error(err))
+	}
+}
+
+func (h *Hub) removeParticipant(ctx context.Context, cl *client.Client, rooms *livekitapi.RoomService, req clientRequest) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	err := rooms.RemoveParticipant(ctx, cl.Room, req.Identity)
+	if err == livekitapi.ErrNotFound {
+		h.sendError(cl, "not_found", "participant is not in the call")
+		return
+	}
+	if err != nil {
+		h.Logger.Error("Failed to remove participant", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to remove participant")
+		return
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, "call.participant_removed", map[string]any{
+		"identity": req.Identity,
+		"by":       cl.ID,
+	}); err != nil {
+		h.Logger.Error("Failed to publish remove event", zap.Error(err))
+	}
+}
+
+func (h *Hub) setParticipantRole(ctx context.Context, cl *client.Client, rooms *livekitapi.RoomService, req clientRequest) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	role, ok := redisrepo.ParseRole(req.Role)
+	if !ok {
+		h.sendError(cl, "invalid_request", "unknown role")
+		return
+	}
+	// the host role only passes on when the host leaves
+	if role == redisrepo.RoleHost {
+		h.sendError(cl, "forbidden", "the host role cannot be assigned")
+		return
+	}
+	// persistent rooms keep the roles of members who left
+	if present, err := h.redisRepo.IsClientInRoom(ctx, req.Identity, cl.Room); err != nil || !present {
+		h.sendError(cl, "not_found", "client is not in the room")
+		return
+	}
+	if err := h.redisRepo.SetRole(ctx, cl.Room, req.Identity, role); err != nil {
+		h.Logger.Error("Failed to store role", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to change role")
+		return
+	}
+	err := rooms.UpdateParticipant(ctx, cl.Room, req.Identity, permissionForRole(role), "", map[string]string{"role": string(role)})
+	if err != nil && err != livekitapi.ErrNotFound {
+		h.Logger.Error("Failed to update call permissions", zap.String("room", cl.Room), zap.Error(err))
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, "room.role_changed", map[string]any{
+		"identity": req.Identity,
+		"role":     role,
+		"by":       cl.ID,
+	}); err != nil {
+		h.Logger.Error("Failed to publish role change", zap.Error(err))
+	}
+}
+
+// handOverHost announces that the member was made host because the room's
+// host left, and gives it host rights in the call.
+func (h *Hub) handOverHost(roomID, clientID, leftID string) {
+	if _, lk := h.LiveKit(); lk != nil {
+		ctx, cancel := context.WithTimeout(h.ctx, liveKitCallTimeout)
+		err := lk.Rooms.UpdateParticipant(ctx, roomID, clientID, permissionForRole(redisrepo.RoleHost), "", map[string]string{"role": string(redisrepo.RoleHost)})
+		cancel()
+		if err != nil && err != livekitapi.ErrNotFound {
+			h.Logger.Error("Failed to update call permissions", zap.String("room", roomID), zap.Error(err))
+		}
+	}
+	if err := h.publishEvent(h.ctx, roomID, "room.role_changed", map[string]any{
+		"identity": clientID,
+		"role":     redisrepo.RoleHost,
+		"by":       "system",
+		"reason":   "host_left",
+		"previous": leftID,
+	}); err != nil {
+		h.Logger.Error("Failed to publish role change", zap.Error(err))
+	}
+}
@@ -0,0 +1,46 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+
+	"go.uber.org/zap"
+)
+
+func (h *Hub) sendPresence(cl *client.Client) {
+	members, err := h.redisRepo.GetRoomMembers(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load room members", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to load room members")
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":    "presence",
+		"room_id": cl.Room,
+		"members": members,
+	}))
+}
+
+func (h *Hub) sendRoomStats(cl *client.Client) {
+	stats, err := h.redisRepo.GetRoomStats(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load room stats", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to load room stats")
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":  "room.stats",
+		"stats": stats,
+	}))
+}
+
+func (h *Hub) announcePresence(cl *client.Client, eventType string, role redisrepo.Role) {
+	data := map[string]any{"id": cl.ID, "name": cl.Name}
+	if role != "" {
+		data["role"] = role
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, eventType, data); err != nil {
+		h.Logger.Error("Failed to publish presence", zap.String("room", cl.Room), zap.Error(err))
+	}
+}
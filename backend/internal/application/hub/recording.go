@@ -0,0 +1,183 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	"go.uber.org/zap"
+)
+
+func recordingStatus(status livekit.EgressStatus) string {
+	return strings.ToLower(strings.TrimPrefix(status.String(), "EGRESS_"))
+}
+
+func isRecordingActive(rec *redisrepo.Recording) bool {
+	return rec.Status == "starting" || rec.Status == "active" || rec.Status == "ending"
+}
+
+func (h *Hub) handleRecordingCommand(cl *client.Client, req clientRequest) {
+	cfg, lk := h.LiveKit()
+	if lk == nil {
+		h.sendError(cl, "unavailable", "video calls are not configured")
+		return
+	}
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, liveKitCallTimeout)
+	defer cancel()
+
+	switch req.Type {
+	case "recording.start":
+		h.startRecording(ctx, cl, lk.Egress, cfg.RecordingPath)
+	case "recording.stop":
+		h.stopRecording(ctx, cl, lk.Egress, req.EgressID)
+	}
+}
+
+func (h *Hub) startRecording(ctx context.Context, cl *client.Client, egress *livekitapi.EgressService, path string) {
+	recordings, err := h.redisRepo.GetRecordings(ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load recordings", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to start recording")
+		return
+	}
+	for _, rec := range recordings {
+		if isRecordingActive(rec) {
+			h.sendError(cl, "conflict", "the call is already being recorded")
+			return
+		}
+	}
+
+	info, err := egress.StartRoomRecording(ctx, cl.Room, path)
+	if err != nil {
+		h.Logger.Error("Failed to start egress", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to start recording")
+		return
+	}
+	rec := &redisrepo.Recording{
+		EgressID:  info.GetEgressId(),
+		RoomID:    cl.Room,
+		Status:    recordingStatus(info.GetStatus()),
+		StartedBy: cl.ID,
+		StartedAt: time.Now(),
+	}
+	if err := h.redisRepo.SaveRecording(ctx, rec); err != nil {
+		h.Logger.Error("Failed to save recording", zap.String("egress", rec.EgressID), zap.Error(err))
+	}
+	h.Logger.Info("recording started", zap.String("room", cl.Room), zap.String("egress", rec.EgressID))
+	if err := h.publishEvent(h.ctx, cl.Room, "recording.started", map[string]any{"recording": rec}); err != nil {
+		h.Logger.Error("Failed to publish recording event", zap.Error(err))
+	}
+}
+
+func (h *Hub) stopRecording(ctx context.Context, cl *client.Client, egress *livekitapi.EgressService, egressID string) {
+	recordings, err := h.redisRepo.GetRecordings(ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to load recordings", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to stop recording")
+		return
+	}
+	stopped := 0
+	for _, rec := range recordings {
+		if !isRecordingActive(rec) || (egressID != "" && rec.EgressID != egressID) {
+			continue
+		}
+		info, err := egress.StopEgress(ctx, rec.EgressID)
+		if err != nil {
+			h.Logger.Error("Failed to stop egress", zap.String("egress", rec.EgressID), zap.Error(err))
+			continue
+		}
+		rec.Status = recordingStatus(info.GetStatus())
+		if err := h.redisRepo.SaveRecording(ctx, rec); err != nil {
+			h.Logger.Error("Failed to save recording", zap.String("egress", rec.EgressID), zap.Error(err))
+		}
+		if err := h.publishEvent(h.ctx, cl.Room, "recording.stopping", map[string]any{"recording": rec}); err != nil {
+			h.Logger.Error("Failed to publish recording event", zap.Error(err))
+		}
+		stopped++
+	}
+	if stopped == 0 {
+		h.sendError(cl, "not_found", "no active recording")
+	}
+}
+
+func (h *Hub) handleEgressEvent(ctx context.Context, info *livekit.EgressInfo) error {
+	roomID := info.GetRoomName()
+	if roomID == "" {
+		return nil
+	}
+	rec, err := h.redisRepo.GetRecording(ctx, roomID, info.GetEgressId())
+	if err == redisrepo.ErrRecordingNotFound {
+		rec = &redisrepo.Recording{
+			EgressID:  info.GetEgressId(),
+			RoomID:    roomID,
+			StartedAt: time.Unix(0, info.GetStartedAt()),
+		}
+	} else if err != nil {
+		return err
+	}
+	rec.Status = recordingStatus(info.GetStatus())
+	rec.Error = info.GetError()
+	if info.GetEndedAt() > 0 {
+		rec.EndedAt = time.Unix(0, info.GetEndedAt())
+	}
+	if files := info.GetFileResults(); len(files) > 0 {
+		rec.Filename = files[0].GetFilename()
+		rec.Location = files[0].GetLocation()
+		rec.Size = files[0].GetSize()
+		rec.Duration = files[0].GetDuration()
+	}
+	if err := h.redisRepo.SaveRecording(ctx, rec); err != nil {
+		return err
+	}
+
+	switch info.GetStatus() {
+	case livekit.EgressStatus_EGRESS_COMPLETE:
+		location := rec.Location
+		if location == "" {
+			location = rec.Filename
+		}
+		return h.postSystemMessage(ctx, roomID, fmt.Sprintf("Recording is ready: %s", location), map[string]any{"recording": rec})
+	case livekit.EgressStatus_EGRESS_FAILED, livekit.EgressStatus_EGRESS_ABORTED, livekit.EgressStatus_EGRESS_LIMIT_REACHED:
+		return h.publishEvent(ctx, roomID, "recording.failed", map[string]any{"recording": rec})
+	}
+	return nil
+}
+
+// GetCompletedRecordings lists the finished recordings of the room, newest first.
+func (h *Hub) GetCompletedRecordings(ctx context.Context, roomID string) ([]*redisrepo.Recording, error) {
+	recordings, err := h.redisRepo.GetRecordings(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	completed := make([]*redisrepo.Recording, 0, len(recordings))
+	for _, rec := range recordings {
+		if rec.Status == "complete" {
+			completed = append(completed, rec)
+		}
+	}
+	return completed, nil
+}
+
+// sendRecordings lists the finished recordings of the client's own room.
+func (h *Hub) sendRecordings(cl *client.Client) {
+	recordings, err := h.GetCompletedRecordings(h.ctx, cl.Room)
+	if err != nil {
+		h.Logger.Error("Failed to list recordings", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to list recordings")
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":       "recording.list",
+		"room_id":    cl.Room,
+		"recordings": recordings,
+	}))
+}
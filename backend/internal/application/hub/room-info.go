@@ -0,0 +1,139 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// Limits of the RoomInfo fields. The topic is one of them: /topic and
+// room.update both edit it through changeRoomInfo.
+const (
+	maxRoomNameLength    = 64
+	maxTopicLength       = 200
+	maxDescriptionLength = 1000
+	maxAvatarURLLength   = 512
+)
+
+// RoomInfo returns the room's descriptive metadata, empty when it cannot be
+// loaded.
+func (h *Hub) RoomInfo(roomID string) *redisrepo.RoomInfo {
+	info, err := h.redisRepo.GetRoomInfo(h.ctx, roomID)
+	if err != nil {
+		h.Logger.Error("Failed to load room info", zap.String("room", roomID), zap.Error(err))
+		return &redisrepo.RoomInfo{}
+	}
+	return info
+}
+
+// updateRoomInfo handles room.update: fields present in the request are
+// replaced, an empty string clears one.
+func (h *Hub) updateRoomInfo(cl *client.Client, req clientRequest) {
+	update := redisrepo.RoomInfoUpdate{
+		Name:        trimmed(req.Name),
+		Topic:       trimmed(req.Topic),
+		Description: trimmed(req.Description),
+		AvatarURL:   trimmed(req.AvatarURL),
+		Public:      req.Public,
+	}
+	if update.Empty() {
+		h.sendError(cl, "invalid_request", "nothing to update")
+		return
+	}
+	h.changeRoomInfo(cl, update)
+}
+
+func trimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
+
+// changeRoomInfo lets the host edit the room info and broadcasts room.updated.
+// Only the fields set in update are written, so concurrent edits of other
+// fields are kept. It reports whether the change was saved.
+func (h *Hub) changeRoomInfo(cl *client.Client, update redisrepo.RoomInfoUpdate) (*redisrepo.RoomInfo, bool) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return nil, false
+	}
+	var changed redisrepo.RoomInfo
+	update.Apply(&changed)
+	if err := validateRoomInfo(&changed); err != nil {
+		h.sendError(cl, "invalid_request", err.Error())
+		return nil, false
+	}
+	info, err := h.redisRepo.UpdateRoomInfo(h.ctx, cl.Room, update)
+	if err != nil {
+		h.Logger.Error("Failed to update room info", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to update the room")
+		return nil, false
+	}
+	if err := h.publishEvent(h.ctx, cl.Room, "room.updated", map[string]any{"room": info, "by": cl.ID}); err != nil {
+		h.Logger.Error("Failed to publish room update", zap.String("room", cl.Room), zap.Error(err))
+	}
+	return info, true
+}
+
+func validateRoomInfo(info *redisrepo.RoomInfo) error {
+	limits := []struct {
+		field string
+		value string
+		max   int
+	}{
+		{"name", info.Name, maxRoomNameLength},
+		{"topic", info.Topic, maxTopicLength},
+		{"description", info.Description, maxDescriptionLength},
+		{"avatar_url", info.AvatarURL, maxAvatarURLLength},
+	}
+	for _, l := range limits {
+		if utf8.RuneCountInString(l.value) > l.max {
+			return fmt.Errorf("%s is longer than %d characters", l.field, l.max)
+		}
+	}
+	if info.AvatarURL != "" {
+		u, err := url.Parse(info.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("avatar_url must be an absolute http or https url")
+		}
+	}
+	return nil
+}
+
+// SetRoomLifecycle makes the room persistent or ephemeral and tells its
+// members. by is the client that asked, empty for the admin API.
+func (h *Hub) SetRoomLifecycle(ctx context.Context, roomID string, lifecycle redisrepo.Lifecycle, by string) error {
+	if err := h.redisRepo.SetRoomLifecycle(ctx, roomID, lifecycle); err != nil {
+		return err
+	}
+	data := map[string]any{"lifecycle": lifecycle}
+	if by != "" {
+		data["by"] = by
+	}
+	if err := h.publishEvent(ctx, roomID, "room.lifecycle", data); err != nil {
+		h.Logger.Error("Failed to publish room lifecycle", zap.String("room", roomID), zap.Error(err))
+	}
+	return nil
+}
+
+func (h *Hub) updateRoomLifecycle(cl *client.Client, req clientRequest) {
+	if !h.requireRole(cl, redisrepo.RoleHost) {
+		return
+	}
+	lifecycle, ok := redisrepo.ParseLifecycle(req.Lifecycle)
+	if !ok {
+		h.sendError(cl, "invalid_request", "lifecycle must be ephemeral or persistent")
+		return
+	}
+	if err := h.SetRoomLifecycle(h.ctx, cl.Room, lifecycle, cl.ID); err != nil {
+		h.Logger.Error("Failed to set room lifecycle", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "failed to change the room lifecycle")
+	}
+}
@@ -0,0 +1,109 @@
+package hub
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"JanArsMAI/Caller/internal/infrastructure/search"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Search bounds: a page holds searchPage hits unless the caller asks for up
+// to maxSearchLimit, and paging stops maxSearchOffset hits deep.
+const (
+	maxSearchRooms  = 10
+	searchPage      = 20
+	maxSearchLimit  = 100
+	maxSearchOffset = 1000
+)
+
+var (
+	ErrSearchDisabled    = errors.New("search is not enabled")
+	ErrRoomNotSearchable = errors.New("only your own room and public rooms can be searched")
+	ErrSearchTooDeep     = fmt.Errorf("search offset must be between 0 and %d", maxSearchOffset)
+)
+
+// Searcher runs full-text queries over room history.
+type Searcher interface {
+	Search(ctx context.Context, q *search.Query) (*search.Results, error)
+}
+
+// SetSearch must be called before Run.
+func (h *Hub) SetSearch(s Searcher) {
+	h.search = s
+}
+
+// Search runs the query for a member of memberRoom, who may search that room
+// and public ones, by default only their own. An empty memberRoom stands for
+// the admin API, which may search any room or all of them.
+func (h *Hub) Search(ctx context.Context, memberRoom string, q *search.Query) (*search.Results, error) {
+	if h.search == nil {
+		return nil, ErrSearchDisabled
+	}
+	if q.Offset < 0 || q.Offset > maxSearchOffset {
+		return nil, ErrSearchTooDeep
+	}
+	if q.Limit <= 0 || q.Limit > maxSearchLimit {
+		q.Limit = searchPage
+	}
+	if memberRoom != "" {
+		if len(q.Rooms) == 0 {
+			q.Rooms = []string{memberRoom}
+		}
+		if len(q.Rooms) > maxSearchRooms {
+			return nil, ErrRoomNotSearchable
+		}
+		for _, roomID := range q.Rooms {
+			if roomID == memberRoom {
+				continue
+			}
+			info, err := h.redisRepo.GetRoomInfo(ctx, roomID)
+			if err != nil {
+				return nil, err
+			}
+			if !info.Public {
+				return nil, ErrRoomNotSearchable
+			}
+		}
+	}
+	return h.search.Search(ctx, q)
+}
+
+func (h *Hub) searchMessages(cl *client.Client, req clientRequest) {
+	q := &search.Query{
+		Text:   req.Query,
+		Rooms:  req.Rooms,
+		From:   req.From,
+		After:  req.After,
+		Before: req.Before,
+		Newest: req.Sort == "newest",
+		Offset: req.Offset,
+		Limit:  int(req.Limit),
+	}
+	res, err := h.Search(h.ctx, cl.Room, q)
+	switch {
+	case errors.Is(err, ErrSearchDisabled):
+		h.sendError(cl, "unavailable", err.Error())
+		return
+	case errors.Is(err, ErrRoomNotSearchable):
+		h.sendError(cl, "forbidden", err.Error())
+		return
+	case errors.Is(err, search.ErrEmptyQuery), errors.Is(err, ErrSearchTooDeep):
+		h.sendError(cl, "invalid_request", err.Error())
+		return
+	case err != nil:
+		h.Logger.Error("Failed to search messages", zap.String("room", cl.Room), zap.Error(err))
+		h.sendError(cl, "internal", "search failed")
+		return
+	}
+	h.deliver(cl, wire.NewFrame(map[string]any{
+		"type":    "search.results",
+		"query":   req.Query,
+		"total":   res.Total,
+		"offset":  q.Offset,
+		"results": res.Hits,
+	}))
+}
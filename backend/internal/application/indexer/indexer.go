@@ -0,0 +1,152 @@
+package indexer
+
+import (
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"JanArsMAI/Caller/internal/infrastructure/search"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	pollInterval  = 500 * time.Millisecond
+	indexBatch    = 500
+	purgeInterval = 10 * time.Minute
+)
+
+// RetentionSource supplies the retention the index follows, so messages
+// purged from the archive stop turning up in search.
+type RetentionSource interface {
+	RetentionPolicies(ctx context.Context) (time.Duration, map[string]time.Duration, error)
+}
+
+// Indexer feeds the search stream into this node's index. Every node keeps
+// its own index and reads the whole stream, resuming from the cursor stored
+// in the index.
+type Indexer struct {
+	repo      *redisrepo.RedisRepo
+	index     search.Index
+	retention RetentionSource
+	Logger    *zap.Logger
+}
+
+func NewIndexer(repo *redisrepo.RedisRepo, index search.Index, lg *zap.Logger) *Indexer {
+	return &Indexer{
+		repo:   repo,
+		index:  index,
+		Logger: lg,
+	}
+}
+
+// SetRetention must be called before Run.
+func (ix *Indexer) SetRetention(src RetentionSource) {
+	ix.retention = src
+}
+
+// Run indexes new messages until the context is cancelled.
+func (ix *Indexer) Run(ctx context.Context) {
+	cursor, err := ix.index.Cursor(ctx)
+	if err != nil {
+		ix.Logger.Error("Failed to read the search index cursor", zap.Error(err))
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cursor = ix.catchUp(ctx, cursor)
+		case <-purge.C:
+			ix.purge(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (ix *Indexer) catchUp(ctx context.Context, cursor string) string {
+	for {
+		queued, next, err := ix.repo.ReadSearchStream(ctx, cursor, indexBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				ix.Logger.Error("Failed to read the search stream", zap.Error(err))
+			}
+			return cursor
+		}
+		if next == cursor {
+			return cursor
+		}
+		docs := make([]*search.Document, len(queued))
+		for i, q := range queued {
+			docs[i] = &search.Document{
+				ID:        q.ID,
+				RoomID:    q.Message.RoomID,
+				From:      q.Message.From,
+				Name:      senderName(q.Message),
+				Content:   q.Message.Content,
+				Timestamp: q.Message.Timestamp,
+			}
+		}
+		if err := ix.index.Index(ctx, docs, next); err != nil {
+			ix.Logger.Error("Failed to index messages", zap.Int("count", len(docs)), zap.Error(err))
+			return cursor
+		}
+		cursor = next
+	}
+}
+
+func (ix *Indexer) purge(ctx context.Context) {
+	if ix.retention == nil {
+		return
+	}
+	fallback, policies, err := ix.retention.RetentionPolicies(ctx)
+	if err != nil {
+		ix.Logger.Error("Failed to load retention policies", zap.Error(err))
+		return
+	}
+	now := time.Now()
+	deleted := 0
+	for roomID, retention := range policies {
+		if retention <= 0 {
+			continue
+		}
+		n, err := ix.index.Delete(ctx, []string{roomID}, nil, now.Add(-retention))
+		if err != nil {
+			ix.Logger.Error("Failed to purge the search index", zap.String("room", roomID), zap.Error(err))
+		}
+		deleted += n
+	}
+	if fallback > 0 {
+		n, err := ix.index.Delete(ctx, nil, slices.Collect(maps.Keys(policies)), now.Add(-fallback))
+		if err != nil {
+			ix.Logger.Error("Failed to purge the search index", zap.Error(err))
+		}
+		deleted += n
+	}
+	if deleted > 0 {
+		ix.Logger.Info("Search index purged", zap.Int("messages", deleted))
+	}
+}
+
+// Search runs the query against the index. Callers scope q.Rooms.
+func (ix *Indexer) Search(ctx context.Context, q *search.Query) (*search.Results, error) {
+	if q.Limit <= 0 || q.Limit > 100 {
+		q.Limit = 20
+	}
+	q.Offset = max(q.Offset, 0)
+	return ix.index.Search(ctx, q)
+}
+
+// senderName is the name a message is found by: the member's display name,
+// or the sender ID of server and bot messages, such as "system".
+func senderName(msg *redisrepo.Message) string {
+	if msg.Name != "" {
+		return msg.Name
+	}
+	return msg.From
+}
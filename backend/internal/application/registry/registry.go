@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"sync"
+)
+
+// Registry indexes the clients connected to this node by room, so that
+// fan-out only touches the members of the target room.
+type Registry struct {
+	mu    sync.RWMutex
+	rooms map[string]map[string]*client.Client
+	total int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		rooms: make(map[string]map[string]*client.Client),
+	}
+}
+
+// Add stores the client and reports whether it is the first local member of its room.
+func (r *Registry) Add(cl *client.Client) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members, ok := r.rooms[cl.Room]
+	if !ok {
+		members = make(map[string]*client.Client)
+		r.rooms[cl.Room] = members
+	}
+	if _, exists := members[cl.ID]; !exists {
+		r.total++
+	}
+	members[cl.ID] = cl
+	return !ok
+}
+
+// Remove deletes the client and reports whether its room has no local members left.
+func (r *Registry) Remove(cl *client.Client) (last bool, removed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members, ok := r.rooms[cl.Room]
+	if !ok {
+		return false, false
+	}
+	if _, exists := members[cl.ID]; !exists {
+		return false, false
+	}
+	delete(members, cl.ID)
+	r.total--
+	if len(members) == 0 {
+		delete(r.rooms, cl.Room)
+		return true, true
+	}
+	return false, true
+}
+
+// Room returns a snapshot of the local members of the room.
+func (r *Registry) Room(roomID string) []*client.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := r.rooms[roomID]
+	list := make([]*client.Client, 0, len(members))
+	for _, cl := range members {
+		list = append(list, cl)
+	}
+	return list
+}
+
+func (r *Registry) All() []*client.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*client.Client, 0, r.total)
+	for _, members := range r.rooms {
+		for _, cl := range members {
+			list = append(list, cl)
+		}
+	}
+	return list
+}
+
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.total
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+	port   string
+}
+
+// OriginPolicy matches request origins against exact entries such as
+// https://chat.example.com and subdomain patterns such as https://*.example.com.
+// A single "*" allows every origin.
+type OriginPolicy struct {
+	allowAll   bool
+	allowEmpty bool
+	exact      map[string]bool
+	wildcards  []wildcardOrigin
+}
+
+func NewOriginPolicy(origins []string, allowEmpty bool) (*OriginPolicy, error) {
+	p := &OriginPolicy{allowEmpty: allowEmpty, exact: make(map[string]bool)}
+	for _, origin := range origins {
+		if origin == "*" {
+			p.allowAll = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("invalid origin %q", origin)
+		}
+		host := strings.ToLower(u.Hostname())
+		if strings.HasPrefix(host, "*.") {
+			p.wildcards = append(p.wildcards, wildcardOrigin{
+				scheme: strings.ToLower(u.Scheme),
+				suffix: host[1:],
+				port:   u.Port(),
+			})
+			continue
+		}
+		if strings.Contains(host, "*") {
+			return nil, fmt.Errorf("invalid origin %q: wildcard must be the leftmost label", origin)
+		}
+		p.exact[strings.ToLower(u.Scheme)+"://"+strings.ToLower(u.Host)] = true
+	}
+	return p, nil
+}
+
+func (p *OriginPolicy) Allow(origin string) bool {
+	if origin == "" {
+		return p.allowEmpty
+	}
+	if p.allowAll {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if p.exact[scheme+"://"+strings.ToLower(u.Host)] {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, w := range p.wildcards {
+		if w.scheme == scheme && w.port == u.Port() && strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+			return true
+		}
+	}
+	return false
+}
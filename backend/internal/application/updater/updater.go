@@ -1,18 +1,64 @@
 package updater
 
 import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
 )
 
-func NewUpdater() *websocket.Upgrader {
+// OriginGuard holds the current origin policy so it can be replaced while
+// the server is running.
+type OriginGuard struct {
+	policy atomic.Pointer[OriginPolicy]
+}
+
+func NewOriginGuard(policy *OriginPolicy) *OriginGuard {
+	g := &OriginGuard{}
+	g.Set(policy)
+	return g
+}
+
+func (g *OriginGuard) Set(policy *OriginPolicy) {
+	g.policy.Store(policy)
+}
+
+func (g *OriginGuard) Allow(r *http.Request) bool {
+	return g.policy.Load().Allow(r.Header.Get("Origin"))
+}
+
+func NewUpdater(guard *OriginGuard, compressor *client.Compressor, lg *zap.Logger) *websocket.Upgrader {
 	return &websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			origin := r.Header.Get("Origin")
-			return origin == "http://localhost:5173" ||
-				origin == "http://localhost:8080" ||
-				origin == ""
+		Subprotocols:      wire.Subprotocols,
+		CheckOrigin:       guard.Allow,
+		EnableCompression: compressor.Enabled(),
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			lg.Warn("WebSocket handshake rejected",
+				zap.String("origin", r.Header.Get("Origin")),
+				zap.String("remote", r.RemoteAddr),
+				zap.Int("status", status),
+				zap.Error(reason))
+			http.Error(w, http.StatusText(status), status)
 		},
 	}
 }
+
+// NegotiableSubprotocol reports whether the request either asks for no
+// subprotocol or offers at least one the server speaks.
+func NegotiableSubprotocol(r *http.Request) bool {
+	offered := websocket.Subprotocols(r)
+	if len(offered) == 0 {
+		return true
+	}
+	for _, p := range offered {
+		for _, s := range wire.Subprotocols {
+			if p == s {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package webhooks
+
+import (
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Events that can be subscribed to.
+const (
+	EventRoomCreated   = "room.created"
+	EventMemberJoined  = "member.joined"
+	EventMemberLeft    = "member.left"
+	EventMessagePosted = "message.posted"
+)
+
+var knownEvents = []string{EventRoomCreated, EventMemberJoined, EventMemberLeft, EventMessagePosted}
+
+var (
+	ErrInvalidURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrUnknownEvent = errors.New("unknown webhook event")
+)
+
+const (
+	subscriptionRefresh = 10 * time.Second
+	// eventBuffer is how many emitted events may wait to be queued before
+	// new ones are dropped.
+	eventBuffer = 1024
+)
+
+// Event is the JSON body POSTed to subscribers.
+type Event struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	RoomID    string         `json:"room_id"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// Dispatcher turns room events into queued deliveries and runs the worker
+// that sends them. Every node runs one; deliveries are shared through Redis.
+type Dispatcher struct {
+	repo   *redisrepo.RedisRepo
+	cfg    atomic.Pointer[config.WebhooksConfig]
+	http   *http.Client
+	events chan Event
+	Logger *zap.Logger
+
+	mu       sync.RWMutex
+	subs     []*redisrepo.WebhookSubscription
+	loadedAt time.Time
+}
+
+func NewDispatcher(cfg config.WebhooksConfig, repo *redisrepo.RedisRepo, lg *zap.Logger) *Dispatcher {
+	d := &Dispatcher{
+		repo:   repo,
+		http:   &http.Client{},
+		events: make(chan Event, eventBuffer),
+		Logger: lg,
+	}
+	d.SetConfig(cfg)
+	return d
+}
+
+func (d *Dispatcher) SetConfig(cfg config.WebhooksConfig) {
+	d.cfg.Store(&cfg)
+}
+
+// Emit hands the event to Run, which queues a delivery for every matching
+// subscription. It never blocks the hub: when Run falls behind by
+// eventBuffer events, new ones are dropped.
+func (d *Dispatcher) Emit(_ context.Context, eventType, roomID string, data map[string]any) {
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		RoomID:    roomID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+	select {
+	case d.events <- event:
+	default:
+		d.Logger.Warn("Webhook event buffer is full, dropping event", zap.String("event", eventType), zap.String("room", roomID))
+	}
+}
+
+func (d *Dispatcher) queueEvents(ctx context.Context) {
+	for {
+		select {
+		case event := <-d.events:
+			d.queue(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) queue(ctx context.Context, event Event) {
+	subs := d.subscriptions(ctx)
+	if len(subs) == 0 {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		d.Logger.Error("Failed to encode webhook event", zap.String("event", event.Type), zap.Error(err))
+		return
+	}
+	for _, sub := range subs {
+		if !matches(sub, event.Type, event.RoomID) {
+			continue
+		}
+		delivery := &redisrepo.WebhookDelivery{
+			ID:             uuid.New().String(),
+			SubscriptionID: sub.ID,
+			Event:          event.Type,
+			Payload:        payload,
+			CreatedAt:      event.Timestamp,
+		}
+		if err := d.repo.ScheduleDelivery(ctx, delivery, time.Now()); err != nil {
+			d.Logger.Error("Failed to queue webhook delivery", zap.String("webhook", sub.ID), zap.Error(err))
+		}
+	}
+}
+
+func matches(sub *redisrepo.WebhookSubscription, eventType, roomID string) bool {
+	if sub.RoomID != "" && sub.RoomID != roomID {
+		return false
+	}
+	return len(sub.Events) == 0 || slices.Contains(sub.Events, eventType)
+}
+
+// subscriptions returns the cached subscription list, reloading it from Redis
+// when it is older than subscriptionRefresh so other nodes' changes show up.
+func (d *Dispatcher) subscriptions(ctx context.Context) []*redisrepo.WebhookSubscription {
+	d.mu.RLock()
+	subs, fresh := d.subs, time.Since(d.loadedAt) < subscriptionRefresh
+	d.mu.RUnlock()
+	if fresh {
+		return subs
+	}
+	return d.reload(ctx)
+}
+
+func (d *Dispatcher) reload(ctx context.Context) []*redisrepo.WebhookSubscription {
+	subs, err := d.repo.GetWebhooks(ctx)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		d.Logger.Error("Failed to load webhook subscriptions", zap.Error(err))
+		// keep serving the old list and retry on the next refresh
+		d.loadedAt = time.Now()
+		return d.subs
+	}
+	d.subs, d.loadedAt = subs, time.Now()
+	return subs
+}
+
+// Subscribe validates and stores a subscription, generating a signing secret
+// when none is given.
+func (d *Dispatcher) Subscribe(ctx context.Context, sub *redisrepo.WebhookSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	for _, event := range sub.Events {
+		if !slices.Contains(knownEvents, event) {
+			return ErrUnknownEvent
+		}
+	}
+	if sub.Secret == "" {
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		sub.Secret = hex.EncodeToString(secret)
+	}
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = time.Now().UTC()
+	if err := d.repo.SaveWebhook(ctx, sub); err != nil {
+		return err
+	}
+	d.reload(ctx)
+	return nil
+}
+
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id string) error {
+	if err := d.repo.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+	d.reload(ctx)
+	return nil
+}
+
+func (d *Dispatcher) Subscriptions(ctx context.Context) ([]*redisrepo.WebhookSubscription, error) {
+	return d.repo.GetWebhooks(ctx)
+}
+
+func (d *Dispatcher) DeadLetters(ctx context.Context, limit int64) ([]*redisrepo.WebhookDelivery, error) {
+	return d.repo.GetDeadLetters(ctx, limit)
+}
+
+// Replay queues a dead-lettered delivery again with a fresh attempt budget.
+func (d *Dispatcher) Replay(ctx context.Context, id string) (*redisrepo.WebhookDelivery, error) {
+	delivery, err := d.repo.TakeDeadLetter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.FailedAt = time.Time{}
+	if err := d.repo.ScheduleDelivery(ctx, delivery, time.Now()); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,143 @@
+package webhooks
+
+import (
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	pollInterval = 500 * time.Millisecond
+	claimBatch   = 20
+	// leaseMargin is added to the time a claimed batch may take before its
+	// deliveries become due again for other nodes.
+	leaseMargin = 30 * time.Second
+)
+
+// Sign returns the X-Caller-Signature value for a body sent at timestamp:
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run queues emitted events and sends due deliveries until the context is
+// cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	go d.queueEvents(ctx)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.deliverDue(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	lease := d.cfg.Load().Timeout*claimBatch + leaseMargin
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, time.Now(), lease, claimBatch)
+	if err != nil && ctx.Err() == nil {
+		d.Logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+	}
+	for _, delivery := range deliveries {
+		d.attempt(ctx, delivery)
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *redisrepo.WebhookDelivery) {
+	cfg := d.cfg.Load()
+	sub, err := d.repo.GetWebhook(ctx, delivery.SubscriptionID)
+	if errors.Is(err, redisrepo.ErrWebhookNotFound) {
+		// the subscription was removed while the delivery was queued
+		d.repo.CompleteDelivery(ctx, delivery.ID)
+		return
+	}
+	if err == nil {
+		err = d.post(ctx, sub, delivery, cfg.Timeout)
+	}
+	delivery.Attempts++
+	if err == nil {
+		if err := d.repo.CompleteDelivery(ctx, delivery.ID); err != nil {
+			d.Logger.Error("Failed to complete webhook delivery", zap.String("delivery", delivery.ID), zap.Error(err))
+		}
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= cfg.MaxAttempts {
+		delivery.FailedAt = time.Now().UTC()
+		d.Logger.Warn("Webhook delivery failed for good",
+			zap.String("delivery", delivery.ID), zap.String("webhook", delivery.SubscriptionID),
+			zap.Int("attempts", delivery.Attempts), zap.Error(err))
+		if err := d.repo.DeadLetterDelivery(ctx, delivery); err != nil {
+			d.Logger.Error("Failed to dead-letter webhook delivery", zap.String("delivery", delivery.ID), zap.Error(err))
+		}
+		return
+	}
+
+	wait := backoff(cfg.InitialBackoff, cfg.MaxBackoff, delivery.Attempts)
+	d.Logger.Debug("Webhook delivery failed, retrying",
+		zap.String("delivery", delivery.ID), zap.Int("attempts", delivery.Attempts),
+		zap.Duration("retry_in", wait), zap.Error(err))
+	if err := d.repo.ScheduleDelivery(ctx, delivery, time.Now().Add(wait)); err != nil {
+		d.Logger.Error("Failed to reschedule webhook delivery", zap.String("delivery", delivery.ID), zap.Error(err))
+	}
+}
+
+// backoff doubles the wait after every attempt, up to max, with up to 20%
+// jitter so failing endpoints are not hit in lockstep.
+func backoff(initial, max time.Duration, attempts int) time.Duration {
+	wait := initial
+	for i := 1; i < attempts && wait < max; i++ {
+		wait *= 2
+	}
+	wait = min(wait, max)
+	return wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+}
+
+func (d *Dispatcher) post(ctx context.Context, sub *redisrepo.WebhookSubscription, delivery *redisrepo.WebhookDelivery, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Caller-Webhooks/1")
+	req.Header.Set("X-Caller-Event", delivery.Event)
+	req.Header.Set("X-Caller-Delivery", delivery.ID)
+	req.Header.Set("X-Caller-Timestamp", strconv.FormatInt(timestamp, 10))
+	if sub.Secret != "" {
+		req.Header.Set("X-Caller-Signature", Sign(sub.Secret, timestamp, delivery.Payload))
+	}
+	resp, err := d.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
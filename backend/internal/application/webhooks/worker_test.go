@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var keys redisrepo.Keys
+
+func newTestDispatcher(t *testing.T) (*Dispatcher, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { db.Close() })
+	cfg := config.WebhooksConfig{
+		MaxAttempts:    3,
+		InitialBackoff: time.Second,
+		MaxBackoff:     4 * time.Second,
+		Timeout:        2 * time.Second,
+	}
+	return NewDispatcher(cfg, redisrepo.NewRedisRepo(db), zap.NewNop()), db
+}
+
+// subscribe stores a subscription to url and queues one delivery for it.
+func subscribe(t *testing.T, d *Dispatcher, url string) {
+	t.Helper()
+	ctx := context.Background()
+	sub := &redisrepo.WebhookSubscription{URL: url, Secret: "s3cret"}
+	if err := d.Subscribe(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	d.queue(ctx, Event{ID: "event-1", Type: EventMemberJoined, RoomID: "room-1", Timestamp: time.Now().UTC()})
+}
+
+// makeDue moves every queued delivery to the front, as if its backoff had
+// passed.
+func makeDue(t *testing.T, db *redis.Client) {
+	t.Helper()
+	ctx := context.Background()
+	for _, id := range db.ZRange(ctx, keys.WebhookQueueKey(), 0, -1).Val() {
+		db.ZAdd(ctx, keys.WebhookQueueKey(), redis.Z{Score: 0, Member: id})
+	}
+}
+
+func TestDeliveryIsSignedAndCompleted(t *testing.T) {
+	d, db := newTestDispatcher(t)
+	ctx := context.Background()
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan request, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- request{r.Header.Clone(), body}
+	}))
+	defer receiver.Close()
+	subscribe(t, d, receiver.URL)
+
+	d.deliverDue(ctx)
+	req := <-got
+	if req.header.Get("X-Caller-Event") != EventMemberJoined || req.header.Get("X-Caller-Delivery") == "" {
+		t.Fatalf("unexpected headers %v", req.header)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(req.header.Get("X-Caller-Timestamp") + "."))
+	mac.Write(req.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); req.header.Get("X-Caller-Signature") != want {
+		t.Fatalf("signature %q, want %q", req.header.Get("X-Caller-Signature"), want)
+	}
+	if !strings.Contains(string(req.body), `"room_id":"room-1"`) {
+		t.Fatalf("unexpected body %s", req.body)
+	}
+	if n := db.ZCard(ctx, keys.WebhookQueueKey()).Val() + db.HLen(ctx, keys.WebhookDeliveriesKey()).Val(); n != 0 {
+		t.Fatal("a completed delivery stayed queued")
+	}
+}
+
+func TestClaimedDeliveryIsLeasedToOneNode(t *testing.T) {
+	first, db := newTestDispatcher(t)
+	ctx := context.Background()
+	second := NewDispatcher(*first.cfg.Load(), first.repo, zap.NewNop())
+	var hits atomic.Int64
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+	}))
+	defer receiver.Close()
+	subscribe(t, first, receiver.URL)
+
+	done := make(chan struct{})
+	go func() {
+		first.deliverDue(ctx)
+		close(done)
+	}()
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// the delivery is in flight on the first node
+	second.deliverDue(ctx)
+	close(release)
+	<-done
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("receiver was called %d times, want once", n)
+	}
+	if db.ZCard(ctx, keys.WebhookQueueKey()).Val() != 0 {
+		t.Fatal("the delivery stayed queued after it succeeded")
+	}
+
+	// a node that dies mid-delivery leaves it leased until the lease ends
+	first.queue(ctx, Event{ID: "event-2", Type: EventMemberLeft, RoomID: "room-1", Timestamp: time.Now().UTC()})
+	claimed, err := first.repo.ClaimDueDeliveries(ctx, time.Now(), time.Minute, claimBatch)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("ClaimDueDeliveries = %d, %v; want 1", len(claimed), err)
+	}
+	if again, _ := first.repo.ClaimDueDeliveries(ctx, time.Now(), time.Minute, claimBatch); len(again) != 0 {
+		t.Fatal("a leased delivery was claimed twice")
+	}
+	if later, _ := first.repo.ClaimDueDeliveries(ctx, time.Now().Add(2*time.Minute), time.Minute, claimBatch); len(later) != 1 {
+		t.Fatal("a delivery whose lease ran out was not claimed again")
+	}
+}
+
+func TestFailingDeliveryBacksOffThenDeadLetters(t *testing.T) {
+	d, db := newTestDispatcher(t)
+	ctx := context.Background()
+	var hits atomic.Int64
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+	subscribe(t, d, receiver.URL)
+
+	// retries wait the initial backoff, then twice that, each with up to 20% jitter
+	for attempt, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		// the queue keeps due times in milliseconds
+		before := time.Now().Truncate(time.Millisecond)
+		d.deliverDue(ctx)
+		if n := hits.Load(); n != int64(attempt+1) {
+			t.Fatalf("receiver was called %d times, want %d", n, attempt+1)
+		}
+		ids := db.ZRangeWithScores(ctx, keys.WebhookQueueKey(), 0, -1).Val()
+		if len(ids) != 1 {
+			t.Fatalf("%d deliveries queued, want 1", len(ids))
+		}
+		due := time.UnixMilli(int64(ids[0].Score))
+		if due.Before(before.Add(wait)) || due.After(time.Now().Add(wait+wait/5)) {
+			t.Fatalf("attempt %d retries in %s, want %s plus jitter", attempt+1, due.Sub(before), wait)
+		}
+		// not due yet
+		d.deliverDue(ctx)
+		if n := hits.Load(); n != int64(attempt+1) {
+			t.Fatal("a delivery was retried before its backoff passed")
+		}
+		makeDue(t, db)
+	}
+
+	d.deliverDue(ctx)
+	if n := hits.Load(); n != 3 {
+		t.Fatalf("receiver was called %d times, want 3", n)
+	}
+	if db.ZCard(ctx, keys.WebhookQueueKey()).Val() != 0 || db.HLen(ctx, keys.WebhookDeliveriesKey()).Val() != 0 {
+		t.Fatal("a delivery out of attempts stayed queued")
+	}
+	dead, err := d.DeadLetters(ctx, 10)
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("DeadLetters = %d, %v; want 1", len(dead), err)
+	}
+	if dead[0].Attempts != 3 || dead[0].FailedAt.IsZero() || !strings.Contains(dead[0].LastError, "500") {
+		t.Fatalf("unexpected dead letter %+v", dead[0])
+	}
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 6: 4 * time.Second} {
+		got := backoff(time.Second, 4*time.Second, attempts)
+		if got < want || got > want+want/5 {
+			t.Fatalf("backoff after %d attempts = %s, want %s plus up to 20%%", attempts, got, want)
+		}
+	}
+}
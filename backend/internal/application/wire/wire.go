@@ -0,0 +1,121 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	SubprotocolJSON    = "caller.v1.json"
+	SubprotocolMsgpack = "caller.v1.msgpack"
+)
+
+// Subprotocols lists the negotiable formats in order of server preference.
+var Subprotocols = []string{SubprotocolJSON, SubprotocolMsgpack}
+
+type Format int
+
+const (
+	JSON Format = iota
+	Msgpack
+	formatCount
+)
+
+// FormatFor maps a negotiated subprotocol to its format; clients that did not
+// ask for a subprotocol speak JSON.
+func FormatFor(subprotocol string) Format {
+	if subprotocol == SubprotocolMsgpack {
+		return Msgpack
+	}
+	return JSON
+}
+
+func (f Format) String() string {
+	if f == Msgpack {
+		return "msgpack"
+	}
+	return "json"
+}
+
+// MessageType is the WebSocket frame type the format is carried in.
+func (f Format) MessageType() int {
+	if f == Msgpack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+func Marshal(f Format, v any) ([]byte, error) {
+	if f == Msgpack {
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+		if err := enc.Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return json.Marshal(v)
+}
+
+func Unmarshal(f Format, data []byte, v any) error {
+	if f == Msgpack {
+		dec := msgpack.NewDecoder(bytes.NewReader(data))
+		dec.SetCustomStructTag("json")
+		return dec.Decode(v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Frame is an outgoing message that is encoded at most once per format, no
+// matter how many recipients it is delivered to.
+type Frame struct {
+	value   any
+	once    [formatCount]sync.Once
+	encoded [formatCount][]byte
+	err     [formatCount]error
+
+	newValue   func() any
+	decodeOnce sync.Once
+	decodeErr  error
+}
+
+func NewFrame(v any) *Frame {
+	return &Frame{value: v}
+}
+
+// NewJSONFrame wraps an already encoded JSON payload, such as one received
+// from Redis. It is only decoded, into the value newValue returns, when a
+// client needs it in another format.
+func NewJSONFrame(data []byte, newValue func() any) *Frame {
+	f := &Frame{newValue: newValue}
+	f.once[JSON].Do(func() { f.encoded[JSON] = data })
+	return f
+}
+
+func (fr *Frame) Bytes(f Format) ([]byte, error) {
+	fr.once[f].Do(func() {
+		if err := fr.decode(); err != nil {
+			fr.err[f] = err
+			return
+		}
+		fr.encoded[f], fr.err[f] = Marshal(f, fr.value)
+	})
+	return fr.encoded[f], fr.err[f]
+}
+
+func (fr *Frame) decode() error {
+	if fr.newValue == nil {
+		return nil
+	}
+	fr.decodeOnce.Do(func() {
+		v := fr.newValue()
+		fr.decodeErr = json.Unmarshal(fr.encoded[JSON], v)
+		fr.value = v
+	})
+	return fr.decodeErr
+}
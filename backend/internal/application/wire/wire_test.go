@@ -0,0 +1,177 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// message mirrors the chat message the hub fans out.
+type message struct {
+	Type      string         `json:"type"`
+	From      string         `json:"from"`
+	RoomID    string         `json:"room_id"`
+	Content   string         `json:"content"`
+	Data      map[string]any `json:"data,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+func sample() *message {
+	return &message{
+		Type:    "chat",
+		From:    "3f0c8f8e-5c55-4a6e-9c0e-6f4a3d2b1a90",
+		RoomID:  "7d7f0a4e-1d2b-4c1f-8f55-2f9a0e6c1b3d",
+		Content: strings.Repeat("hello there, ", 8),
+		Data: map[string]any{
+			"reply_to": "1792403997189-0",
+			"mentions": []any{"alice", "bob"},
+			"edited":   false,
+		},
+		Timestamp: time.Date(2026, 10, 19, 10, 0, 0, 123456789, time.UTC),
+	}
+}
+
+func TestFrameEncodesOncePerFormat(t *testing.T) {
+	fr := NewFrame(sample())
+	first, err := fr.Bytes(Msgpack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := fr.Bytes(Msgpack)
+	if &first[0] != &second[0] {
+		t.Fatal("msgpack encoding was repeated")
+	}
+}
+
+func TestJSONFrameDecodesOnlyForOtherFormats(t *testing.T) {
+	data, _ := json.Marshal(sample())
+	decoded := 0
+	fr := NewJSONFrame(data, func() any {
+		decoded++
+		return &message{}
+	})
+	got, err := fr.Bytes(JSON)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("json frame = %s, %v; want the payload unchanged", got, err)
+	}
+	if decoded != 0 {
+		t.Fatal("json delivery decoded the payload")
+	}
+
+	packed, err := fr.Bytes(Msgpack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr.Bytes(Msgpack)
+	if decoded != 1 {
+		t.Fatalf("payload decoded %d times, want 1", decoded)
+	}
+	var back message
+	if err := Unmarshal(Msgpack, packed, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Content != sample().Content || !back.Timestamp.Equal(sample().Timestamp) {
+		t.Fatalf("msgpack round trip gave %+v", back)
+	}
+}
+
+// BenchmarkEncode measures one encoding of a chat message and reports its
+// size, the bandwidth each recipient costs.
+func BenchmarkEncode(b *testing.B) {
+	for _, f := range []Format{JSON, Msgpack} {
+		b.Run(f.String(), func(b *testing.B) {
+			msg := sample()
+			var size int
+			b.ReportAllocs()
+			for b.Loop() {
+				data, err := Marshal(f, msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes/msg")
+		})
+	}
+}
+
+// BenchmarkDecode measures parsing a client frame at ingress.
+func BenchmarkDecode(b *testing.B) {
+	for _, f := range []Format{JSON, Msgpack} {
+		b.Run(f.String(), func(b *testing.B) {
+			data, _ := Marshal(f, sample())
+			b.ReportAllocs()
+			for b.Loop() {
+				var msg message
+				if err := Unmarshal(f, data, &msg); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+		})
+	}
+}
+
+// BenchmarkFanOut delivers one broadcast to a room of recipients. The
+// per-recipient case is the old JSON path that marshalled for every client;
+// the others encode once through a Frame. Bytes are per broadcast.
+func BenchmarkFanOut(b *testing.B) {
+	for _, recipients := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("json-per-recipient/%d", recipients), func(b *testing.B) {
+			msg := sample()
+			var sent int
+			for b.Loop() {
+				sent = 0
+				for range recipients {
+					data, err := json.Marshal(msg)
+					if err != nil {
+						b.Fatal(err)
+					}
+					n, _ := io.Discard.Write(data)
+					sent += n
+				}
+			}
+			b.ReportMetric(float64(sent), "bytes/op")
+		})
+		for _, f := range []Format{JSON, Msgpack} {
+			b.Run(fmt.Sprintf("%s-once/%d", f, recipients), func(b *testing.B) {
+				msg := sample()
+				var sent int
+				for b.Loop() {
+					sent = 0
+					fr := NewFrame(msg)
+					for range recipients {
+						data, err := fr.Bytes(f)
+						if err != nil {
+							b.Fatal(err)
+						}
+						n, _ := io.Discard.Write(data)
+						sent += n
+					}
+				}
+				b.ReportMetric(float64(sent), "bytes/op")
+			})
+		}
+	}
+}
+
+// BenchmarkRelay covers a payload arriving from Redis: JSON clients get it
+// as is, a msgpack client makes the frame decode and re-encode it once.
+func BenchmarkRelay(b *testing.B) {
+	payload, _ := json.Marshal(sample())
+	for _, f := range []Format{JSON, Msgpack} {
+		b.Run(f.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				fr := NewJSONFrame(payload, func() any { return &message{} })
+				if _, err := fr.Bytes(f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,40 +2,134 @@ package config
 
 import (
 	"errors"
-	"os"
+	"net"
+	"net/url"
 	"time"
 
 	"github.com/livekit/protocol/auth"
-	"gopkg.in/yaml.v3"
+	"github.com/livekit/protocol/livekit"
 )
 
 type LiveKitConfig struct {
-	ApiKey    string `yaml:"key"`
-	ApiUrl    string `yaml:"url"`
-	ApiSecret string `yaml:"secret"`
+	Mode      string        `yaml:"mode" env:"LIVEKIT_MODE" default:"auto"`
+	ApiKey    string        `yaml:"key" env:"LIVEKIT_API_KEY"`
+	ApiUrl    string        `yaml:"url" env:"LIVEKIT_URL"`
+	ApiSecret string        `yaml:"secret" env:"LIVEKIT_API_SECRET"`
+	TokenTTL  time.Duration `yaml:"token_ttl" env:"LIVEKIT_TOKEN_TTL" default:"8h"`
+
+	RecordingPath string `yaml:"recording_path" env:"LIVEKIT_RECORDING_PATH" default:"recordings/{room_name}-{time}.mp4"`
+}
+
+type TokenGrant struct {
+	CanPublish        bool
+	CanPublishSources []livekit.TrackSource
+	CanPublishData    bool
+	CanSubscribe      bool
+	RoomAdmin         bool
+	Name              string
+	Metadata          string
+	Attributes        map[string]string
 }
 
 type RedisConfig struct {
-	Host     string `yaml:"host" env:"REDIS_HOST" default:"localhost"`
+	Host     string `yaml:"host" env:"REDIS_HOST" default:"localhost" required:"true"`
 	Port     int    `yaml:"port" env:"REDIS_PORT" default:"6379"`
 	Password string `yaml:"password" env:"REDIS_PASSWORD"`
 	DB       int    `yaml:"db" env:"REDIS_DB" default:"0"`
 }
 
 type ServerConfig struct {
-	Host string `yaml:"host"`
-	Port string `yaml:"port"`
+	Host             string   `yaml:"host" env:"SERVER_HOST"`
+	Port             string   `yaml:"port" env:"SERVER_PORT" default:"8080" required:"true"`
+	AllowedOrigins   []string `yaml:"allowed_origins" env:"SERVER_ALLOWED_ORIGINS" default:"http://localhost:5173,http://localhost:8080"`
+	AllowEmptyOrigin bool     `yaml:"allow_empty_origin" env:"SERVER_ALLOW_EMPTY_ORIGIN" default:"true"`
+	// AdminToken is the bearer token of the admin endpoints, such as webhook
+	// and bot management. They are closed while it is empty.
+	AdminToken string `yaml:"admin_token" env:"SERVER_ADMIN_TOKEN"`
+}
+
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled" env:"COMPRESSION_ENABLED" default:"true"`
+	Level   int  `yaml:"level" env:"COMPRESSION_LEVEL" default:"1"`
+	MinSize int  `yaml:"min_size" env:"COMPRESSION_MIN_SIZE" default:"1024"`
+}
+
+// GRPCConfig enables the gRPC API for backend services when Port is set. It
+// listens on loopback only unless Token is set, which callers then present
+// as a bearer token.
+type GRPCConfig struct {
+	Host  string `yaml:"host" env:"GRPC_HOST" default:"127.0.0.1"`
+	Port  string `yaml:"port" env:"GRPC_PORT"`
+	Token string `yaml:"token" env:"GRPC_TOKEN"`
+}
+
+type WebhooksConfig struct {
+	// Enabled needs server.admin_token: subscriptions make the server POST to
+	// any URL, so only the admin may create them.
+	Enabled        bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED" default:"false"`
+	MaxAttempts    int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" default:"8"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" env:"WEBHOOKS_INITIAL_BACKOFF" default:"1s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" env:"WEBHOOKS_MAX_BACKOFF" default:"10m"`
+	Timeout        time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" default:"10s"`
+	// AdminToken is the former name of server.admin_token and is used when
+	// that is not set.
+	AdminToken string `yaml:"admin_token" env:"WEBHOOKS_ADMIN_TOKEN"`
+}
+
+// ArchiveConfig enables the long-term message archive when Driver is set:
+// postgres for clusters, sqlite for a single node. Retention 0 keeps
+// messages forever; rooms can override it. QueueLength caps the Redis queue
+// of messages waiting to be archived: when the archive falls that far
+// behind, the oldest queued messages are dropped.
+type ArchiveConfig struct {
+	Driver        string        `yaml:"driver" env:"ARCHIVE_DRIVER"`
+	DSN           string        `yaml:"dsn" env:"ARCHIVE_DSN"`
+	BatchSize     int           `yaml:"batch_size" env:"ARCHIVE_BATCH_SIZE" default:"200"`
+	Retention     time.Duration `yaml:"retention" env:"ARCHIVE_RETENTION" default:"0s"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"ARCHIVE_PURGE_INTERVAL" default:"1h"`
+	QueueLength   int64         `yaml:"queue_length" env:"ARCHIVE_QUEUE_LENGTH" default:"1000000"`
+}
+
+// SearchConfig controls the full-text message index each node keeps. An
+// empty Path keeps it in memory, rebuilt from the search stream on start.
+// Enabling it needs server.admin_token, which guards cross-room searches.
+type SearchConfig struct {
+	Enabled      bool   `yaml:"enabled" env:"SEARCH_ENABLED" default:"false"`
+	Path         string `yaml:"path" env:"SEARCH_PATH"`
+	StreamLength int64  `yaml:"stream_length" env:"SEARCH_STREAM_LENGTH" default:"100000"`
 }
 
 type LoggerConfig struct {
-	Level string `yaml:"level"`
+	Level string `yaml:"level" env:"LOG_LEVEL" default:"info"`
+}
+
+type HubConfig struct {
+	SendQueueSize      int    `yaml:"send_queue_size" env:"HUB_SEND_QUEUE_SIZE" default:"256"`
+	SlowConsumerPolicy string `yaml:"slow_consumer_policy" env:"HUB_SLOW_CONSUMER_POLICY" default:"drop_newest"`
+}
+
+type LimitsConfig struct {
+	// MessagesPerSecond rate-limits each client's requests, 0 turns it off.
+	MessagesPerSecond float64 `yaml:"messages_per_second" env:"LIMITS_MESSAGES_PER_SECOND" default:"0"`
+	Burst             int     `yaml:"burst" env:"LIMITS_BURST" default:"20"`
+	// MaxRoomMembers caps rooms that set no limit of their own, 0 is unlimited.
+	MaxRoomMembers int `yaml:"max_room_members" env:"LIMITS_MAX_ROOM_MEMBERS" default:"0"`
 }
 
 type Config struct {
-	LiveKitCfg   LiveKitConfig `yaml:"livekit"`
-	RedisCfg     RedisConfig   `yaml:"redis"`
-	ServerCfg    ServerConfig  `yaml:"server"`
-	LoggerConfig LoggerConfig  `yaml:"logger"`
+	LiveKitCfg   LiveKitConfig     `yaml:"livekit"`
+	RedisCfg     RedisConfig       `yaml:"redis"`
+	ServerCfg    ServerConfig      `yaml:"server"`
+	GRPCCfg      GRPCConfig        `yaml:"grpc"`
+	LoggerConfig LoggerConfig      `yaml:"logger"`
+	HubCfg       HubConfig         `yaml:"hub"`
+	LimitsCfg    LimitsConfig      `yaml:"limits"`
+	Compression  CompressionConfig `yaml:"compression"`
+	Webhooks     WebhooksConfig    `yaml:"webhooks"`
+	Archive      ArchiveConfig     `yaml:"archive"`
+	Search       SearchConfig      `yaml:"search"`
+
+	source string
 }
 
 var (
@@ -44,53 +138,204 @@ var (
 	ErrMissingField  = errors.New("config: missing required field")
 )
 
+// FieldError names the configuration field that failed validation together
+// with the environment variable that can be used to set it.
+type FieldError struct {
+	Field  string
+	Env    string
+	Reason string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Err.Error() + " " + e.Field
+	if e.Env != "" {
+		msg += " (env " + e.Env + ")"
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
 func LoadFromFile(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+	return Load([]string{"-config", path})
+}
+
+func (c *Config) validate() error {
+	errs := checkRequired(c)
+	invalid := func(path, reason string) {
+		errs = append(errs, &FieldError{Field: path, Env: envFor(c, path), Reason: reason, Err: ErrInvalidConfig})
+	}
+	switch c.LiveKitCfg.Mode {
+	case "auto", "enabled", "disabled":
+	default:
+		invalid("livekit.mode", "must be one of auto, enabled, disabled")
+	}
+	if c.LiveKitCfg.Mode == "enabled" || (c.LiveKitCfg.Mode == "auto" && c.LiveKitCfg.partiallySet()) {
+		for path, value := range map[string]string{
+			"livekit.key":    c.LiveKitCfg.ApiKey,
+			"livekit.url":    c.LiveKitCfg.ApiUrl,
+			"livekit.secret": c.LiveKitCfg.ApiSecret,
+		} {
+			if value == "" {
+				errs = append(errs, &FieldError{Field: path, Env: envFor(c, path), Reason: "required when LiveKit is enabled", Err: ErrMissingField})
+			}
+		}
 	}
-	if len(data) == 0 {
-		return nil, ErrNoDataInCfg
+	if c.LiveKitCfg.TokenTTL < time.Minute {
+		invalid("livekit.token_ttl", "must be at least 1m")
 	}
-	var cfg Config
-	err = yaml.Unmarshal(data, &cfg)
-	if err != nil {
-		return nil, err
+	if c.HubCfg.SendQueueSize <= 0 {
+		invalid("hub.send_queue_size", "must be positive")
 	}
-	if err := cfg.validate(); err != nil {
-		return nil, err
+	for _, origin := range c.ServerCfg.AllowedOrigins {
+		if !validOrigin(origin) {
+			invalid("server.allowed_origins", "bad origin "+origin)
+		}
 	}
-	return &cfg, nil
+	if c.GRPCCfg.Port != "" && c.GRPCCfg.Token == "" && !loopback(c.GRPCCfg.Host) {
+		errs = append(errs, &FieldError{Field: "grpc.token", Env: envFor(c, "grpc.token"), Reason: "required when grpc.host is not a loopback address", Err: ErrMissingField})
+	}
+	if c.Compression.Level < -2 || c.Compression.Level > 9 {
+		invalid("compression.level", "must be between -2 and 9")
+	}
+	if c.Compression.MinSize < 0 {
+		invalid("compression.min_size", "must not be negative")
+	}
+	if c.Webhooks.Enabled && c.ServerCfg.AdminToken == "" {
+		errs = append(errs, &FieldError{Field: "server.admin_token", Env: envFor(c, "server.admin_token"), Reason: "required when webhooks are enabled", Err: ErrMissingField})
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		invalid("webhooks.max_attempts", "must be at least 1")
+	}
+	if c.Webhooks.InitialBackoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+		invalid("webhooks.max_backoff", "backoff must be positive and max_backoff at least initial_backoff")
+	}
+	if c.Webhooks.Timeout <= 0 {
+		invalid("webhooks.timeout", "must be positive")
+	}
+	switch c.Archive.Driver {
+	case "":
+	case "postgres", "sqlite":
+		if c.Archive.DSN == "" {
+			errs = append(errs, &FieldError{Field: "archive.dsn", Env: envFor(c, "archive.dsn"), Reason: "required when the archive is enabled", Err: ErrMissingField})
+		}
+	default:
+		invalid("archive.driver", "must be postgres or sqlite")
+	}
+	if c.Archive.BatchSize < 1 {
+		invalid("archive.batch_size", "must be at least 1")
+	}
+	if c.Archive.Retention < 0 {
+		invalid("archive.retention", "must not be negative")
+	}
+	if c.Archive.PurgeInterval < time.Minute {
+		invalid("archive.purge_interval", "must be at least 1m")
+	}
+	if c.Archive.Driver != "" && c.Archive.QueueLength < 1 {
+		invalid("archive.queue_length", "must be at least 1 when the archive is enabled")
+	}
+	if c.Search.Enabled && c.ServerCfg.AdminToken == "" {
+		errs = append(errs, &FieldError{Field: "server.admin_token", Env: envFor(c, "server.admin_token"), Reason: "required when search is enabled", Err: ErrMissingField})
+	}
+	if c.Search.Enabled && c.Search.StreamLength < 1 {
+		invalid("search.stream_length", "must be at least 1 when search is enabled")
+	}
+	if c.LimitsCfg.MessagesPerSecond < 0 {
+		invalid("limits.messages_per_second", "must not be negative")
+	}
+	if c.LimitsCfg.MessagesPerSecond > 0 && c.LimitsCfg.Burst < 1 {
+		invalid("limits.burst", "must be at least 1 when rate limiting is on")
+	}
+	if c.LimitsCfg.MaxRoomMembers < 0 {
+		invalid("limits.max_room_members", "must not be negative")
+	}
+	switch c.HubCfg.SlowConsumerPolicy {
+	case "drop_oldest", "drop_newest", "disconnect":
+	default:
+		invalid("hub.slow_consumer_policy", "must be one of drop_oldest, drop_newest, disconnect")
+	}
+	return errors.Join(errs...)
 }
 
-func (c *Config) validate() error {
-	if c.LiveKitCfg.ApiKey == "" {
-		return ErrMissingField
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
 	}
-	if c.LiveKitCfg.ApiUrl == "" {
-		return ErrMissingField
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
 	}
-	if c.LiveKitCfg.ApiSecret == "" {
-		return ErrMissingField
+	u, err := url.Parse(origin)
+	return err == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/")
+}
+
+// LiveKitEnabled reports whether the video features should be started. In
+// auto mode LiveKit is enabled only when all credentials are present.
+func (c *Config) LiveKitEnabled() bool {
+	switch c.LiveKitCfg.Mode {
+	case "enabled":
+		return true
+	case "disabled":
+		return false
 	}
-	return nil
+	return c.LiveKitCfg.ApiKey != "" && c.LiveKitCfg.ApiUrl != "" && c.LiveKitCfg.ApiSecret != ""
+}
+
+func (c *LiveKitConfig) partiallySet() bool {
+	set := 0
+	for _, value := range []string{c.ApiKey, c.ApiUrl, c.ApiSecret} {
+		if value != "" {
+			set++
+		}
+	}
+	return set > 0 && set < 3
 }
 
 func NewLiveKitConfigFromConfig(liveKitCfg LiveKitConfig) *LiveKitConfig {
 	return &LiveKitConfig{
+		Mode:      liveKitCfg.Mode,
 		ApiKey:    liveKitCfg.ApiKey,
 		ApiUrl:    liveKitCfg.ApiUrl,
 		ApiSecret: liveKitCfg.ApiSecret,
+		TokenTTL:  liveKitCfg.TokenTTL,
+
+		RecordingPath: liveKitCfg.RecordingPath,
 	}
 }
 
-func (c *LiveKitConfig) GenerateToken(room string, id string) (string, error) {
+func (c *LiveKitConfig) GenerateToken(room string, id string, g TokenGrant) (string, error) {
 	at := auth.NewAccessToken(c.ApiKey, c.ApiSecret)
 
 	grant := &auth.VideoGrant{
-		RoomJoin: true,
-		Room:     room,
+		RoomJoin:  true,
+		Room:      room,
+		RoomAdmin: g.RoomAdmin,
+	}
+	grant.SetCanPublish(g.CanPublish)
+	grant.SetCanPublishData(g.CanPublishData)
+	grant.SetCanSubscribe(g.CanSubscribe)
+	if len(g.CanPublishSources) > 0 {
+		grant.SetCanPublishSources(g.CanPublishSources)
+	}
+	at.SetVideoGrant(grant).SetIdentity(id).SetValidFor(c.TokenTTL)
+	if g.Name != "" {
+		at.SetName(g.Name)
+	}
+	if g.Metadata != "" {
+		at.SetMetadata(g.Metadata)
+	}
+	if len(g.Attributes) > 0 {
+		at.SetAttributes(g.Attributes)
 	}
-	at.SetVideoGrant(grant).SetIdentity(id).SetValidFor(time.Hour * 8)
 	return at.ToJWT()
 }
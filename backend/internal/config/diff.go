@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type Change struct {
+	Field   string
+	Old     string
+	New     string
+	Restart bool
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+	if c.Restart {
+		s += " (requires restart)"
+	}
+	return s
+}
+
+var restartOnly = []string{"redis.", "server.host", "server.port", "compression.enabled", "grpc.", "webhooks.enabled", "archive.driver", "archive.dsn", "archive.queue_length", "search.enabled", "search.path", "search.stream_length"}
+
+var secretFields = map[string]bool{
+	"livekit.secret":       true,
+	"redis.password":       true,
+	"server.admin_token":   true,
+	"webhooks.admin_token": true,
+	"archive.dsn":          true,
+	"grpc.token":           true,
+}
+
+// Diff lists the fields that differ between two configurations. Secrets are
+// reported as changed without their values.
+func Diff(old, next *Config) []Change {
+	oldFields := collectFields(reflect.ValueOf(old).Elem(), "")
+	nextFields := collectFields(reflect.ValueOf(next).Elem(), "")
+	var changes []Change
+	for i, f := range oldFields {
+		a, b := f.value.Interface(), nextFields[i].value.Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		change := Change{Field: f.path, Old: fmt.Sprint(a), New: fmt.Sprint(b)}
+		if secretFields[f.path] {
+			change.Old, change.New = "***", "***"
+		}
+		for _, prefix := range restartOnly {
+			if strings.HasPrefix(f.path, prefix) {
+				change.Restart = true
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// Source is the YAML file the configuration was read from, if any.
+func (c *Config) Source() string {
+	return c.source
+}
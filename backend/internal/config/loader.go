@@ -0,0 +1,236 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultConfigPath = "config.yaml"
+	configPathEnv     = "CALLER_CONFIG"
+)
+
+// Load builds the configuration in layers: `default` tags, the YAML file,
+// environment variables from `env` tags and finally command-line flags named
+// after the YAML path (e.g. -redis.host). The file is taken from -config,
+// then CALLER_CONFIG, then config.yaml; only an explicitly chosen file must exist.
+func Load(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config: failed to read .env: %w", err)
+	}
+
+	cfg := &Config{}
+	fields := collectFields(reflect.ValueOf(cfg).Elem(), "")
+
+	flags := flag.NewFlagSet("caller", flag.ContinueOnError)
+	path := flags.String("config", "", "path to the YAML config file (env "+configPathEnv+")")
+	values := make(map[string]*flagValue, len(fields))
+	for _, f := range fields {
+		v := &flagValue{isBool: f.value.Kind() == reflect.Bool}
+		values[f.path] = v
+		usage := "config " + f.path
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		flags.Var(v, f.path, usage)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := f.set(f.def); err != nil {
+			return nil, &FieldError{Field: f.path, Reason: "bad default: " + err.Error(), Err: ErrInvalidConfig}
+		}
+	}
+
+	explicit := true
+	if *path == "" {
+		*path = os.Getenv(configPathEnv)
+	}
+	if *path == "" {
+		*path, explicit = defaultConfigPath, false
+	}
+	if err := loadYAML(*path, cfg); err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	} else {
+		cfg.source = *path
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if raw, ok := os.LookupEnv(f.env); ok {
+			if err := f.set(raw); err != nil {
+				return nil, &FieldError{Field: f.path, Env: f.env, Reason: err.Error(), Err: ErrInvalidConfig}
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if v := values[f.path]; v.set {
+			if err := f.set(v.raw); err != nil {
+				return nil, &FieldError{Field: f.path, Env: f.env, Reason: err.Error(), Err: ErrInvalidConfig}
+			}
+		}
+	}
+
+	if cfg.ServerCfg.AdminToken == "" {
+		cfg.ServerCfg.AdminToken = cfg.Webhooks.AdminToken
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func loadYAML(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return ErrNoDataInCfg
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+type field struct {
+	path     string
+	env      string
+	def      string
+	required bool
+	value    reflect.Value
+}
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Duration(0)) {
+			fields = append(fields, collectFields(fv, path)...)
+			continue
+		}
+		fields = append(fields, field{
+			path:     path,
+			env:      sf.Tag.Get("env"),
+			def:      sf.Tag.Get("default"),
+			required: sf.Tag.Get("required") == "true",
+			value:    fv,
+		})
+	}
+	return fields
+}
+
+func (f field) set(raw string) error {
+	v := f.value
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int || v.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+func checkRequired(cfg *Config) []error {
+	var errs []error
+	for _, f := range collectFields(reflect.ValueOf(cfg).Elem(), "") {
+		if f.required && f.value.IsZero() {
+			errs = append(errs, &FieldError{Field: f.path, Env: f.env, Err: ErrMissingField})
+		}
+	}
+	return errs
+}
+
+func envFor(cfg *Config, path string) string {
+	for _, f := range collectFields(reflect.ValueOf(cfg).Elem(), "") {
+		if f.path == path {
+			return f.env
+		}
+	}
+	return ""
+}
+
+type flagValue struct {
+	raw    string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.raw
+}
+
+func (v *flagValue) Set(raw string) error {
+	v.raw, v.set = raw, true
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// unsetenv clears key for the test and restores it afterwards.
+func unsetenv(t *testing.T, key string) {
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestLoadLayersDefaultsYAMLEnvAndFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		env  string
+		flag string
+		want int
+	}{
+		{name: "default", want: 6379},
+		{name: "yaml over default", yaml: "6380", want: 6380},
+		{name: "env over yaml", yaml: "6380", env: "6381", want: 6381},
+		{name: "flag over env", yaml: "6380", env: "6381", flag: "6382", want: 6382},
+		{name: "flag over yaml", yaml: "6380", flag: "6382", want: 6382},
+		{name: "env over default", env: "6381", want: 6381},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// no config.yaml or .env from the working directory
+			t.Chdir(t.TempDir())
+			unsetenv(t, configPathEnv)
+			unsetenv(t, "REDIS_PORT")
+			unsetenv(t, "REDIS_HOST")
+
+			var args []string
+			if tt.yaml != "" {
+				path := filepath.Join(t.TempDir(), "caller.yaml")
+				data := "redis:\n  host: redis.internal\n  port: " + tt.yaml + "\n"
+				if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-config", path)
+			}
+			if tt.env != "" {
+				t.Setenv("REDIS_PORT", tt.env)
+			}
+			if tt.flag != "" {
+				args = append(args, "-redis.port="+tt.flag)
+			}
+
+			cfg, err := Load(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.RedisCfg.Port != tt.want {
+				t.Fatalf("redis.port = %d, want %d", cfg.RedisCfg.Port, tt.want)
+			}
+			// a field set by the file alone survives the layers above it
+			wantHost := "localhost"
+			if tt.yaml != "" {
+				wantHost = "redis.internal"
+			}
+			if cfg.RedisCfg.Host != wantHost {
+				t.Fatalf("redis.host = %q, want %q", cfg.RedisCfg.Host, wantHost)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsBadValuesByLayer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	unsetenv(t, configPathEnv)
+
+	t.Setenv("REDIS_PORT", "not-a-port")
+	_, err := Load(nil)
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Field != "redis.port" || fe.Env != "REDIS_PORT" {
+		t.Fatalf("Load = %v, want a redis.port error naming REDIS_PORT", err)
+	}
+
+	// a valid flag doesn't hide a bad env value, which is read first
+	if _, err := Load([]string{"-redis.port=6390"}); err == nil {
+		t.Fatal("Load accepted a bad REDIS_PORT")
+	}
+
+	unsetenv(t, "REDIS_PORT")
+	if _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatal("Load accepted a missing config file that was asked for")
+	}
+}
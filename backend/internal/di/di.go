@@ -1,36 +1,65 @@
 package di
 
 import (
+	"JanArsMAI/Caller/internal/application/archiver"
+	"JanArsMAI/Caller/internal/application/bots"
 	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/application/indexer"
+	"JanArsMAI/Caller/internal/application/updater"
+	"JanArsMAI/Caller/internal/application/webhooks"
 	"JanArsMAI/Caller/internal/config"
+	"JanArsMAI/Caller/internal/infrastructure/archive"
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
 	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"JanArsMAI/Caller/internal/infrastructure/search"
 	"JanArsMAI/Caller/internal/logger"
+	"JanArsMAI/Caller/internal/presentation/rpc"
 	"JanArsMAI/Caller/internal/presentation/server"
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
 type Container struct {
-	Config      *config.Config
 	Logger      *zap.Logger
 	RedisClient *redis.Client
 	RedisRepo   *redisrepo.RedisRepo
 	Hub         *hub.Hub
 	Server      *server.WsServer
+	GRPCServer  *rpc.Server
+	Webhooks    *webhooks.Dispatcher
+	Bots        *bots.Registry
+	Archiver    *archiver.Archiver
+	Archive     *archive.Store
+	Indexer     *indexer.Indexer
+	SearchIndex search.Index
+
+	args     []string
+	logLevel zap.AtomicLevel
+	config   atomic.Pointer[config.Config]
+	reloadMu sync.Mutex
+}
+
+// Config returns the configuration in effect, which a reload may replace.
+func (c *Container) Config() *config.Config {
+	return c.config.Load()
 }
 
-func NewContainer(ctx context.Context) (*Container, error) {
-	c := &Container{}
-	cfg, err := config.LoadFromFile("config.yaml")
+func NewContainer(ctx context.Context, args []string) (*Container, error) {
+	c := &Container{args: args}
+	cfg, err := config.Load(args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	c.Config = cfg
-	zapLogger := logger.NewLogger(cfg.LoggerConfig.Level)
-	c.Logger = zapLogger
+	c.config.Store(cfg)
+	c.logLevel = zap.NewAtomicLevelAt(logger.ParseLevel(cfg.LoggerConfig.Level))
+	c.Logger = logger.NewAtomicLogger(c.logLevel)
 	redisClient := redisrepo.NewRedisConnection(&cfg.RedisCfg)
 	if redisClient == nil {
 		return nil, fmt.Errorf("failed to create Redis connection")
@@ -42,15 +71,81 @@ func NewContainer(ctx context.Context) (*Container, error) {
 	c.Logger.Info("Connected to Redis")
 	c.RedisRepo = redisrepo.NewRedisRepo(redisClient)
 
-	c.Hub = hub.NewHub(&cfg.LiveKitCfg, c.RedisRepo, c.Logger)
+	liveKitCfg, liveKit := newLiveKit(cfg)
+	if liveKit == nil {
+		c.Logger.Info("LiveKit is disabled, running chat-only")
+	}
+	c.Hub = hub.NewHub(liveKitCfg, cfg.HubCfg, cfg.LimitsCfg, liveKit, c.RedisRepo, c.Logger)
 
 	srvDsn := fmt.Sprintf("%s:%s", cfg.ServerCfg.Host, cfg.ServerCfg.Port)
-	c.Server = server.NewWsServer(c.Hub, srvDsn, c.Logger)
+	origins, err := updater.NewOriginPolicy(cfg.ServerCfg.AllowedOrigins, cfg.ServerCfg.AllowEmptyOrigin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build origin policy: %w", err)
+	}
+	c.Server = server.NewWsServer(c.Hub, srvDsn, origins, cfg.Compression, c.Logger)
+	c.Server.SetAdminToken(cfg.ServerCfg.AdminToken)
+	c.Bots = bots.NewRegistry(c.RedisRepo, hub.BuiltinCommands(), c.Logger)
+	c.Hub.SetBots(c.Bots)
+	c.Server.Bots = c.Bots
+	if cfg.Webhooks.Enabled {
+		c.Webhooks = webhooks.NewDispatcher(cfg.Webhooks, c.RedisRepo, c.Logger)
+		c.Hub.SetEventSink(c.Webhooks)
+		c.Server.Webhooks = c.Webhooks
+	}
+	if cfg.Archive.Driver != "" {
+		store, err := archive.Open(ctx, cfg.Archive.Driver, cfg.Archive.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open message archive: %w", err)
+		}
+		c.Archive = store
+		if err := c.RedisRepo.EnableArchiveQueue(ctx, cfg.Archive.QueueLength); err != nil {
+			return nil, fmt.Errorf("failed to create archive queue: %w", err)
+		}
+		c.Archiver = archiver.NewArchiver(cfg.Archive, c.RedisRepo, store, c.Logger)
+		c.Hub.SetArchive(c.Archiver)
+		c.Server.Archive = c.Archiver
+		c.Logger.Info("Message archive enabled", zap.String("driver", cfg.Archive.Driver))
+	}
+	if cfg.Search.Enabled {
+		index, err := search.OpenBleve(cfg.Search.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open search index: %w", err)
+		}
+		c.SearchIndex = index
+		c.RedisRepo.EnableSearchStream(cfg.Search.StreamLength)
+		c.Indexer = indexer.NewIndexer(c.RedisRepo, index, c.Logger)
+		if c.Archiver != nil {
+			c.Indexer.SetRetention(c.Archiver)
+		}
+		c.Hub.SetSearch(c.Indexer)
+		c.Server.Search = c.Indexer
+	}
+	if cfg.GRPCCfg.Port != "" {
+		c.GRPCServer = rpc.NewServer(c.Hub, c.RedisRepo, cfg.GRPCCfg.Token, c.Logger)
+	}
 
 	return c, nil
 }
 
+func newLiveKit(cfg *config.Config) (*config.LiveKitConfig, *livekitapi.Services) {
+	if !cfg.LiveKitEnabled() {
+		return nil, nil
+	}
+	services := livekitapi.NewServices(cfg.LiveKitCfg.ApiUrl, cfg.LiveKitCfg.ApiKey, cfg.LiveKitCfg.ApiSecret, &http.Client{Timeout: 10 * time.Second})
+	return &cfg.LiveKitCfg, services
+}
+
 func (c *Container) Close() error {
+	if c.SearchIndex != nil {
+		if err := c.SearchIndex.Close(); err != nil {
+			c.Logger.Error("Failed to close search index", zap.Error(err))
+		}
+	}
+	if c.Archive != nil {
+		if err := c.Archive.Close(); err != nil {
+			c.Logger.Error("Failed to close message archive", zap.Error(err))
+		}
+	}
 	if c.RedisClient != nil {
 		if err := c.RedisClient.Close(); err != nil {
 			c.Logger.Error("Failed to close Redis", zap.Error(err))
@@ -0,0 +1,139 @@
+package di
+
+import (
+	"JanArsMAI/Caller/internal/application/updater"
+	"JanArsMAI/Caller/internal/config"
+	"JanArsMAI/Caller/internal/logger"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+const reloadDebounce = 500 * time.Millisecond
+
+// WatchConfig reloads the configuration on SIGHUP and whenever the config
+// file changes, until the context is cancelled.
+func (c *Container) WatchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileEvents <-chan fsnotify.Event
+	if source := c.Config().Source(); source != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			c.Logger.Error("Failed to watch config file", zap.Error(err))
+		} else {
+			defer watcher.Close()
+			// editors replace files by rename, so watch the directory
+			if err := watcher.Add(filepath.Dir(source)); err != nil {
+				c.Logger.Error("Failed to watch config file", zap.String("path", source), zap.Error(err))
+			}
+			fileEvents = watcher.Events
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-hup:
+			c.Logger.Info("SIGHUP received, reloading configuration")
+			c.Reload()
+		case ev, ok := <-fileEvents:
+			if !ok {
+				fileEvents = nil
+				continue
+			}
+			if filepath.Clean(ev.Name) == filepath.Clean(c.Config().Source()) && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce = time.After(reloadDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			c.Logger.Info("Config file changed, reloading configuration")
+			c.Reload()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reload loads and validates a fresh configuration and swaps in the sections
+// that can change at runtime. Settings that need a restart are reported and
+// kept at their running values.
+func (c *Container) Reload() {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	current := c.Config()
+	next, err := config.Load(c.args)
+	if err != nil {
+		c.Logger.Error("Config reload rejected", zap.Error(err))
+		return
+	}
+	changes := config.Diff(current, next)
+	if len(changes) == 0 {
+		c.Logger.Info("Config reloaded, nothing changed")
+		return
+	}
+
+	next.RedisCfg = current.RedisCfg
+	next.ServerCfg.Host = current.ServerCfg.Host
+	next.ServerCfg.Port = current.ServerCfg.Port
+	next.Compression.Enabled = current.Compression.Enabled
+	next.GRPCCfg = current.GRPCCfg
+	next.Webhooks.Enabled = current.Webhooks.Enabled
+	next.Archive.Driver = current.Archive.Driver
+	next.Archive.DSN = current.Archive.DSN
+	next.Archive.QueueLength = current.Archive.QueueLength
+	next.Search.Enabled = current.Search.Enabled
+	next.Search.Path = current.Search.Path
+	next.Search.StreamLength = current.Search.StreamLength
+	if next.Webhooks.Enabled && next.ServerCfg.AdminToken == "" {
+		c.Logger.Error("Config reload rejected: webhooks stay enabled until a restart and need server.admin_token")
+		return
+	}
+	if next.Search.Enabled && next.ServerCfg.AdminToken == "" {
+		c.Logger.Error("Config reload rejected: search stays enabled until a restart and needs server.admin_token")
+		return
+	}
+
+	origins, err := updater.NewOriginPolicy(next.ServerCfg.AllowedOrigins, next.ServerCfg.AllowEmptyOrigin)
+	if err != nil {
+		c.Logger.Error("Config reload rejected", zap.Error(err))
+		return
+	}
+
+	liveKitChanged := false
+	summary := make([]string, 0, len(changes))
+	for _, change := range changes {
+		summary = append(summary, change.String())
+		if strings.HasPrefix(change.Field, "livekit.") {
+			liveKitChanged = true
+		}
+	}
+
+	c.logLevel.SetLevel(logger.ParseLevel(next.LoggerConfig.Level))
+	c.Hub.SetHubConfig(next.HubCfg)
+	c.Hub.SetLimits(next.LimitsCfg)
+	c.Server.Origins.Set(origins)
+	c.Server.Compressor.Set(next.Compression)
+	c.Server.SetAdminToken(next.ServerCfg.AdminToken)
+	if c.Webhooks != nil {
+		c.Webhooks.SetConfig(next.Webhooks)
+	}
+	if c.Archiver != nil {
+		c.Archiver.SetConfig(next.Archive)
+	}
+	if liveKitChanged {
+		c.Hub.SetLiveKit(newLiveKit(next))
+	}
+	c.config.Store(next)
+
+	c.Logger.Info("Config reloaded", zap.Strings("changes", summary))
+}
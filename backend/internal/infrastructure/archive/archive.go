@@ -0,0 +1,151 @@
+package archive
+
+import (
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+var ErrUnknownDriver = errors.New("archive: unknown driver")
+
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS archived_messages (
+		id TEXT PRIMARY KEY,
+		room_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		sender TEXT NOT NULL,
+		content TEXT NOT NULL,
+		data TEXT,
+		sent_at BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS archived_messages_room_sent ON archived_messages (room_id, sent_at)`,
+	`CREATE INDEX IF NOT EXISTS archived_messages_sent ON archived_messages (sent_at)`,
+	`CREATE TABLE IF NOT EXISTS retention_policies (
+		room_id TEXT PRIMARY KEY,
+		retention_seconds BIGINT NOT NULL
+	)`,
+}
+
+// Store keeps messages in PostgreSQL or SQLite. Timestamps are stored as
+// Unix nanoseconds so both databases sort and compare them the same way.
+type Store struct {
+	db       *sql.DB
+	postgres bool
+}
+
+// Open connects to the database and creates the tables if needed.
+func Open(ctx context.Context, driver, dsn string) (*Store, error) {
+	var s *Store
+	switch driver {
+	case "postgres":
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			return nil, err
+		}
+		s = &Store{db: db, postgres: true}
+	case "sqlite":
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, err
+		}
+		// SQLite allows one writer; a single connection avoids busy errors
+		db.SetMaxOpenConns(1)
+		s = &Store{db: db}
+	default:
+		return nil, ErrUnknownDriver
+	}
+	for _, stmt := range schema {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			s.db.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// query rewrites ? placeholders to $n for PostgreSQL.
+func (s *Store) query(q string) string {
+	if !s.postgres {
+		return q
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Save writes queued messages in one transaction. Messages archived before
+// are skipped, so a batch can be retried safely.
+func (s *Store) Save(ctx context.Context, batch []*redisrepo.QueuedMessage) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, s.query(`INSERT INTO archived_messages (id, room_id, type, sender, content, data, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, q := range batch {
+		msg := q.Message
+		var data sql.NullString
+		if len(msg.Data) > 0 {
+			raw, err := json.Marshal(msg.Data)
+			if err != nil {
+				return err
+			}
+			data = sql.NullString{String: string(raw), Valid: true}
+		}
+		if _, err := stmt.ExecContext(ctx, q.ID, msg.RoomID, msg.Type, msg.From, msg.Content, data, msg.Timestamp.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Messages returns up to limit messages of the room sent before the cutoff,
+// newest first.
+func (s *Store) Messages(ctx context.Context, roomID string, before time.Time, limit int) ([]*redisrepo.Message, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT type, sender, content, data, sent_at FROM archived_messages
+		WHERE room_id = ? AND sent_at < ? ORDER BY sent_at DESC LIMIT ?`), roomID, before.UnixNano(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := make([]*redisrepo.Message, 0, limit)
+	for rows.Next() {
+		msg := &redisrepo.Message{RoomID: roomID}
+		var data sql.NullString
+		var sentAt int64
+		if err := rows.Scan(&msg.Type, &msg.From, &msg.Content, &data, &sentAt); err != nil {
+			return nil, err
+		}
+		if data.Valid {
+			json.Unmarshal([]byte(data.String), &msg.Data)
+		}
+		msg.Timestamp = time.Unix(0, sentAt).UTC()
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SetRetention sets how long the room's messages are kept; 0 keeps them
+// forever.
+func (s *Store) SetRetention(ctx context.Context, roomID string, retention time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO retention_policies (room_id, retention_seconds) VALUES (?, ?)
+		ON CONFLICT (room_id) DO UPDATE SET retention_seconds = excluded.retention_seconds`), roomID, int64(retention.Seconds()))
+	return err
+}
+
+// ClearRetention drops the room's policy so the default applies again.
+func (s *Store) ClearRetention(ctx context.Context, roomID string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM retention_policies WHERE room_id = ?`), roomID)
+	return err
+}
+
+// Retention returns the room's own policy, if it has one.
+func (s *Store) Retention(ctx context.Context, roomID string) (time.Duration, bool, error) {
+	var seconds int64
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT retention_seconds FROM retention_policies WHERE room_id = ?`), roomID).Scan(&seconds)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return time.Duration(seconds) * time.Second, true, nil
+}
+
+// Policies returns every room-specific policy.
+func (s *Store) Policies(ctx context.Context) (map[string]time.Duration, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT room_id, retention_seconds FROM retention_policies`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	policies := make(map[string]time.Duration)
+	for rows.Next() {
+		var roomID string
+		var seconds int64
+		if err := rows.Scan(&roomID, &seconds); err != nil {
+			return nil, err
+		}
+		policies[roomID] = time.Duration(seconds) * time.Second
+	}
+	return policies, rows.Err()
+}
+
+// Purge deletes messages older than their room's retention, or than
+// defaultRetention for rooms without a policy, and returns how many went.
+func (s *Store) Purge(ctx context.Context, defaultRetention time.Duration, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.query(`DELETE FROM archived_messages WHERE EXISTS (
+		SELECT 1 FROM retention_policies p WHERE p.room_id = archived_messages.room_id
+		AND p.retention_seconds > 0 AND archived_messages.sent_at < ? - p.retention_seconds * 1000000000)`), now.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := res.RowsAffected()
+	if err != nil || defaultRetention <= 0 {
+		return purged, err
+	}
+	res, err = s.db.ExecContext(ctx, s.query(`DELETE FROM archived_messages WHERE sent_at < ?
+		AND room_id NOT IN (SELECT room_id FROM retention_policies)`), now.Add(-defaultRetention).UnixNano())
+	if err != nil {
+		return purged, err
+	}
+	n, err := res.RowsAffected()
+	return purged + n, err
+}
@@ -0,0 +1,41 @@
+package livekitapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/twitchtv/twirp"
+)
+
+// HTTPClient is the transport used for LiveKit server API calls. Tests can
+// substitute a client pointed at a local fake server.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type credentials struct {
+	apiKey    string
+	apiSecret string
+}
+
+func (c credentials) withAuth(ctx context.Context, grant *auth.VideoGrant) (context.Context, error) {
+	at := auth.NewAccessToken(c.apiKey, c.apiSecret)
+	at.SetVideoGrant(grant).SetValidFor(time.Minute)
+	token, err := at.ToJWT()
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	header.Set("Authorization", "Bearer "+token)
+	return twirp.WithHTTPRequestHeaders(ctx, header)
+}
+
+func toHttpURL(url string) string {
+	if strings.HasPrefix(url, "ws") {
+		return strings.Replace(url, "ws", "http", 1)
+	}
+	return url
+}
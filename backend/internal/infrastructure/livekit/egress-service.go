@@ -0,0 +1,46 @@
+package livekitapi
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+)
+
+type EgressService struct {
+	client livekit.Egress
+	creds  credentials
+}
+
+func NewEgressService(apiUrl, apiKey, apiSecret string, httpClient HTTPClient) *EgressService {
+	return &EgressService{
+		client: livekit.NewEgressJSONClient(toHttpURL(apiUrl), httpClient),
+		creds:  credentials{apiKey: apiKey, apiSecret: apiSecret},
+	}
+}
+
+// StartRoomRecording starts a room composite egress that writes an MP4 file
+// to filepath, which may use LiveKit templates such as {room_name} and {time}.
+func (s *EgressService) StartRoomRecording(ctx context.Context, room, filepath string) (*livekit.EgressInfo, error) {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomRecord: true})
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.StartRoomCompositeEgress(ctx, &livekit.RoomCompositeEgressRequest{
+		RoomName: room,
+		FileOutputs: []*livekit.EncodedFileOutput{{
+			FileType: livekit.EncodedFileType_MP4,
+			Filepath: filepath,
+		}},
+	})
+	return info, mapError(err)
+}
+
+func (s *EgressService) StopEgress(ctx context.Context, egressID string) (*livekit.EgressInfo, error) {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomRecord: true})
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.client.StopEgress(ctx, &livekit.StopEgressRequest{EgressId: egressID})
+	return info, mapError(err)
+}
@@ -0,0 +1,10 @@
+package livekitapi
+
+import "errors"
+
+var (
+	ErrNoAuthHeader    = errors.New("livekit: authorization header is missing")
+	ErrUnknownApiKey   = errors.New("livekit: webhook signed with unknown api key")
+	ErrInvalidChecksum = errors.New("livekit: webhook body checksum mismatch")
+	ErrNotFound        = errors.New("livekit: room or participant not found")
+)
@@ -0,0 +1,104 @@
+package livekitapi
+
+import (
+	"context"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"github.com/twitchtv/twirp"
+)
+
+type RoomService struct {
+	client livekit.RoomService
+	creds  credentials
+}
+
+func NewRoomService(apiUrl, apiKey, apiSecret string, httpClient HTTPClient) *RoomService {
+	return &RoomService{
+		client: livekit.NewRoomServiceJSONClient(toHttpURL(apiUrl), httpClient),
+		creds:  credentials{apiKey: apiKey, apiSecret: apiSecret},
+	}
+}
+
+func (s *RoomService) ListParticipants(ctx context.Context, room string) ([]*livekit.ParticipantInfo, error) {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.ListParticipants(ctx, &livekit.ListParticipantsRequest{Room: room})
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return res.GetParticipants(), nil
+}
+
+func (s *RoomService) RemoveParticipant(ctx context.Context, room, identity string) error {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.RemoveParticipant(ctx, &livekit.RoomParticipantIdentity{Room: room, Identity: identity})
+	return mapError(err)
+}
+
+func (s *RoomService) MutePublishedTrack(ctx context.Context, room, identity, trackSid string, muted bool) error {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.MutePublishedTrack(ctx, &livekit.MuteRoomTrackRequest{
+		Room:     room,
+		Identity: identity,
+		TrackSid: trackSid,
+		Muted:    muted,
+	})
+	return mapError(err)
+}
+
+// MuteSource mutes every track of the participant published from the given source.
+func (s *RoomService) MuteSource(ctx context.Context, room, identity string, source livekit.TrackSource, muted bool) (int, error) {
+	authCtx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return 0, err
+	}
+	p, err := s.client.GetParticipant(authCtx, &livekit.RoomParticipantIdentity{Room: room, Identity: identity})
+	if err != nil {
+		return 0, mapError(err)
+	}
+	count := 0
+	for _, track := range p.GetTracks() {
+		if track.GetSource() != source {
+			continue
+		}
+		if err := s.MutePublishedTrack(ctx, room, identity, track.GetSid(), muted); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (s *RoomService) UpdateParticipant(ctx context.Context, room, identity string, permission *livekit.ParticipantPermission, metadata string, attributes map[string]string) error {
+	ctx, err := s.creds.withAuth(ctx, &auth.VideoGrant{RoomAdmin: true, Room: room})
+	if err != nil {
+		return err
+	}
+	_, err = s.client.UpdateParticipant(ctx, &livekit.UpdateParticipantRequest{
+		Room:       room,
+		Identity:   identity,
+		Permission: permission,
+		Metadata:   metadata,
+		Attributes: attributes,
+	})
+	return mapError(err)
+}
+
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if twerr, ok := err.(twirp.Error); ok && twerr.Code() == twirp.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,208 @@
+package livekitapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret-that-is-long-enough-for-hs256"
+)
+
+// fakeLiveKit answers RoomService calls the way a LiveKit server does over
+// Twirp JSON and records the requests it got.
+type fakeLiveKit struct {
+	t        *testing.T
+	mu       sync.Mutex
+	calls    map[string][]string
+	handlers map[string]func(body []byte) (proto.Message, int)
+}
+
+func newFakeLiveKit(t *testing.T) (*fakeLiveKit, *RoomService) {
+	f := &fakeLiveKit{t: t, calls: map[string][]string{}, handlers: map[string]func([]byte) (proto.Message, int){}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, NewRoomService(strings.Replace(srv.URL, "http", "ws", 1), testKey, testSecret, srv.Client())
+}
+
+func (f *fakeLiveKit) handle(method string, fn func(body []byte) (proto.Message, int)) {
+	f.handlers[method] = fn
+}
+
+func (f *fakeLiveKit) serve(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/twirp/livekit.RoomService/")
+	if !f.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"code":"unauthenticated","msg":"bad token"}`)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.calls[method] = append(f.calls[method], string(body))
+	f.mu.Unlock()
+	fn, ok := f.handlers[method]
+	if !ok {
+		f.t.Errorf("unexpected call to %s", method)
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	res, status := fn(body)
+	if status == http.StatusNotFound {
+		w.WriteHeader(status)
+		io.WriteString(w, `{"code":"not_found","msg":"participant not found"}`)
+		return
+	}
+	data, err := protojson.Marshal(res)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (f *fakeLiveKit) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	v, err := auth.ParseAPIToken(token)
+	if err != nil || v.APIKey() != testKey {
+		return false
+	}
+	_, claims, err := v.Verify(testSecret)
+	return err == nil && claims.Video != nil && claims.Video.RoomAdmin
+}
+
+func (f *fakeLiveKit) requests(method string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func decode[T proto.Message](t *testing.T, raw string, msg T) T {
+	t.Helper()
+	if err := protojson.Unmarshal([]byte(raw), msg); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	return msg
+}
+
+func TestMuteSourceMutesOnlyThatSource(t *testing.T) {
+	f, rooms := newFakeLiveKit(t)
+	f.handle("GetParticipant", func([]byte) (proto.Message, int) {
+		return &livekit.ParticipantInfo{Identity: "alice", Tracks: []*livekit.TrackInfo{
+			{Sid: "TR_mic", Source: livekit.TrackSource_MICROPHONE},
+			{Sid: "TR_cam", Source: livekit.TrackSource_CAMERA},
+			{Sid: "TR_mic2", Source: livekit.TrackSource_MICROPHONE},
+		}}, http.StatusOK
+	})
+	f.handle("MutePublishedTrack", func([]byte) (proto.Message, int) {
+		return &livekit.MuteRoomTrackResponse{}, http.StatusOK
+	})
+
+	n, err := rooms.MuteSource(context.Background(), "room1", "alice", livekit.TrackSource_MICROPHONE, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("muted %d tracks, want 2", n)
+	}
+	var sids []string
+	for _, raw := range f.requests("MutePublishedTrack") {
+		req := decode(t, raw, &livekit.MuteRoomTrackRequest{})
+		if req.Room != "room1" || req.Identity != "alice" || !req.Muted {
+			t.Errorf("unexpected mute request %v", req)
+		}
+		sids = append(sids, req.TrackSid)
+	}
+	if strings.Join(sids, ",") != "TR_mic,TR_mic2" {
+		t.Errorf("muted %v, want the microphone tracks", sids)
+	}
+}
+
+func TestMuteSourceUnknownParticipant(t *testing.T) {
+	f, rooms := newFakeLiveKit(t)
+	f.handle("GetParticipant", func([]byte) (proto.Message, int) {
+		return nil, http.StatusNotFound
+	})
+
+	_, err := rooms.MuteSource(context.Background(), "room1", "ghost", livekit.TrackSource_CAMERA, true)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRemoveParticipant(t *testing.T) {
+	f, rooms := newFakeLiveKit(t)
+	f.handle("RemoveParticipant", func(body []byte) (proto.Message, int) {
+		req := decode(t, string(body), &livekit.RoomParticipantIdentity{})
+		if req.Identity == "ghost" {
+			return nil, http.StatusNotFound
+		}
+		return &livekit.RemoveParticipantResponse{}, http.StatusOK
+	})
+
+	if err := rooms.RemoveParticipant(context.Background(), "room1", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	req := decode(t, f.requests("RemoveParticipant")[0], &livekit.RoomParticipantIdentity{})
+	if req.Room != "room1" || req.Identity != "bob" {
+		t.Errorf("unexpected remove request %v", req)
+	}
+	if err := rooms.RemoveParticipant(context.Background(), "room1", "ghost"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestUpdateParticipantSetsRole(t *testing.T) {
+	f, rooms := newFakeLiveKit(t)
+	f.handle("UpdateParticipant", func([]byte) (proto.Message, int) {
+		return &livekit.ParticipantInfo{Identity: "carol"}, http.StatusOK
+	})
+
+	permission := &livekit.ParticipantPermission{
+		CanSubscribe:      true,
+		CanPublish:        true,
+		CanPublishSources: []livekit.TrackSource{livekit.TrackSource_MICROPHONE},
+	}
+	err := rooms.UpdateParticipant(context.Background(), "room1", "carol", permission, "", map[string]string{"role": "speaker"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := decode(t, f.requests("UpdateParticipant")[0], &livekit.UpdateParticipantRequest{})
+	if req.Room != "room1" || req.Identity != "carol" || req.Attributes["role"] != "speaker" {
+		t.Errorf("unexpected update request %v", req)
+	}
+	if !proto.Equal(req.Permission, permission) {
+		t.Errorf("permission %v, want %v", req.Permission, permission)
+	}
+}
+
+func TestRoomServiceSignsRequests(t *testing.T) {
+	f, _ := newFakeLiveKit(t)
+	f.handle("RemoveParticipant", func([]byte) (proto.Message, int) {
+		return &livekit.RemoveParticipantResponse{}, http.StatusOK
+	})
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	defer srv.Close()
+	wrongKey := NewRoomService(srv.URL, testKey, "another-secret-that-is-long-enough-too", srv.Client())
+
+	if err := wrongKey.RemoveParticipant(context.Background(), "room1", "bob"); err == nil {
+		t.Fatal("request signed with the wrong secret succeeded")
+	}
+	if len(f.requests("RemoveParticipant")) != 0 {
+		t.Fatal("fake server accepted a request with a bad signature")
+	}
+}
@@ -0,0 +1,13 @@
+package livekitapi
+
+type Services struct {
+	Rooms  *RoomService
+	Egress *EgressService
+}
+
+func NewServices(apiUrl, apiKey, apiSecret string, httpClient HTTPClient) *Services {
+	return &Services{
+		Rooms:  NewRoomService(apiUrl, apiKey, apiSecret, httpClient),
+		Egress: NewEgressService(apiUrl, apiKey, apiSecret, httpClient),
+	}
+}
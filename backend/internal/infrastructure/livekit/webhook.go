@@ -0,0 +1,54 @@
+package livekitapi
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"io"
+	"net/http"
+
+	"github.com/livekit/protocol/auth"
+	"github.com/livekit/protocol/livekit"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const maxWebhookBody = 1 << 20
+
+// ReceiveWebhookEvent verifies that the request was signed by LiveKit with the
+// given key pair and decodes the event from its body.
+func ReceiveWebhookEvent(r *http.Request, apiKey, apiSecret string) (*livekit.WebhookEvent, error) {
+	defer r.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, err
+	}
+
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return nil, ErrNoAuthHeader
+	}
+	verifier, err := auth.ParseAPIToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if verifier.APIKey() != apiKey {
+		return nil, ErrUnknownApiKey
+	}
+	_, claims, err := verifier.Verify(apiSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := base64.StdEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(claims.Sha256), []byte(hash)) != 1 {
+		return nil, ErrInvalidChecksum
+	}
+
+	event := &livekit.WebhookEvent{}
+	opts := protojson.UnmarshalOptions{DiscardUnknown: true, AllowPartial: true}
+	if err := opts.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
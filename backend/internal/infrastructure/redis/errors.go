@@ -9,4 +9,10 @@ var (
 	ErrRoomAlreadyExists = errors.New("room already exists")
 	ErrInvalidData       = errors.New("invalid data format")
 	ErrRedisNotConnected = errors.New("redis not connected")
+	ErrRecordingNotFound = errors.New("recording not found")
+	ErrWebhookNotFound   = errors.New("webhook not found")
+	ErrDeliveryNotFound  = errors.New("webhook delivery not found")
+	ErrBotNotFound       = errors.New("bot not found")
+	ErrBotExists         = errors.New("a bot with this name is already registered")
+	ErrNotInLobby        = errors.New("client is not waiting in the lobby")
 )
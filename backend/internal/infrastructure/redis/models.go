@@ -1,30 +1,196 @@
 package redisrepo
 
 import (
+	"bytes"
 	"encoding/json"
 	"time"
 )
 
+type Role string
+
+const (
+	RoleHost        Role = "host"
+	RoleParticipant Role = "participant"
+	RoleSpeaker     Role = "speaker"
+	RoleViewer      Role = "viewer"
+)
+
+func ParseRole(s string) (Role, bool) {
+	switch role := Role(s); role {
+	case RoleHost, RoleParticipant, RoleSpeaker, RoleViewer:
+		return role, true
+	}
+	return "", false
+}
+
+// Lifecycle decides what happens to a room when its last member leaves.
+type Lifecycle string
+
+const (
+	// LifecycleEphemeral rooms are deleted when they empty; history expires
+	// a day after the last message.
+	LifecycleEphemeral Lifecycle = "ephemeral"
+	// LifecyclePersistent rooms keep their metadata, settings and history
+	// and stay listed with zero members.
+	LifecyclePersistent Lifecycle = "persistent"
+)
+
+func ParseLifecycle(s string) (Lifecycle, bool) {
+	switch lc := Lifecycle(s); lc {
+	case LifecycleEphemeral, LifecyclePersistent:
+		return lc, true
+	}
+	return "", false
+}
+
+type RoomSettings struct {
+	DefaultRole Role `json:"default_role"`
+	// MaxMembers caps the room, 0 falls back to limits.max_room_members.
+	MaxMembers int `json:"max_members"`
+	// Knock makes everyone joining a non-empty room wait in the lobby until a
+	// host admits them.
+	Knock bool `json:"knock"`
+}
+
+// LobbyEntry is a client waiting to enter a room.
+type LobbyEntry struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Position int       `json:"position"`
+	Since    time.Time `json:"since"`
+	Admitted bool      `json:"admitted,omitempty"`
+}
+
 type ClientInfo struct {
 	ID        string    `json:"id"`
+	Member    string    `json:"-"`
+	Name      string    `json:"name,omitempty"`
 	RoomID    string    `json:"room_id"`
 	JoinedAt  time.Time `json:"joined_at"`
 	UserAgent string    `json:"user_agent,omitempty"`
 }
 
 type Message struct {
-	Type      string    `json:"type"`
-	From      string    `json:"from"`
-	RoomID    string    `json:"room_id"`
-	Content   string    `json:"content"`
-	Timestamp time.Time `json:"timestamp"`
+	Type      string         `json:"type"`
+	From      string         `json:"from"`
+	Name      string         `json:"name,omitempty"`
+	RoomID    string         `json:"room_id"`
+	Content   string         `json:"content"`
+	Data      map[string]any `json:"data,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type RoomMember struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Role     Role      `json:"role,omitempty"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// RoomInfo is the descriptive metadata owners can set on a room. Empty
+// fields are unset.
+type RoomInfo struct {
+	Name        string `json:"name,omitempty"`
+	Topic       string `json:"topic,omitempty"`
+	Description string `json:"description,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	// Public rooms are listed in the room directory.
+	Public bool `json:"public,omitempty"`
+}
+
+// RoomInfoUpdate changes the RoomInfo fields that are not nil; an empty
+// string clears one.
+type RoomInfoUpdate struct {
+	Name        *string
+	Topic       *string
+	Description *string
+	AvatarURL   *string
+	Public      *bool
+}
+
+func (u RoomInfoUpdate) Empty() bool {
+	return u.Name == nil && u.Topic == nil && u.Description == nil && u.AvatarURL == nil && u.Public == nil
+}
+
+// Apply copies the changed fields into info.
+func (u RoomInfoUpdate) Apply(info *RoomInfo) {
+	for _, f := range []struct {
+		value *string
+		dst   *string
+	}{
+		{u.Name, &info.Name},
+		{u.Topic, &info.Topic},
+		{u.Description, &info.Description},
+		{u.AvatarURL, &info.AvatarURL},
+	} {
+		if f.value != nil {
+			*f.dst = *f.value
+		}
+	}
+	if u.Public != nil {
+		info.Public = *u.Public
+	}
+}
+
+// DirectoryEntry is a public room as listed in the room directory.
+type DirectoryEntry struct {
+	RoomID       string    `json:"room_id"`
+	Name         string    `json:"name,omitempty"`
+	Topic        string    `json:"topic,omitempty"`
+	AvatarURL    string    `json:"avatar_url,omitempty"`
+	Members      int64     `json:"members"`
+	LastActivity time.Time `json:"last_activity"`
 }
 
 type RoomStats struct {
-	RoomID    string    `json:"room_id"`
-	Clients   int64     `json:"clients_count"`
-	CreatedAt time.Time `json:"created_at"`
-	LastSeen  time.Time `json:"last_seen"`
+	RoomInfo
+	RoomID     string    `json:"room_id"`
+	Clients    int64     `json:"clients_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeen   time.Time `json:"last_seen"`
+	CallActive bool      `json:"call_active"`
+	Lifecycle  Lifecycle `json:"lifecycle"`
+}
+
+type CallTrack struct {
+	SID    string `json:"sid"`
+	Type   string `json:"type"`
+	Source string `json:"source"`
+	Muted  bool   `json:"muted"`
+}
+
+type CallParticipant struct {
+	Identity string      `json:"identity"`
+	Name     string      `json:"name,omitempty"`
+	JoinedAt time.Time   `json:"joined_at"`
+	Tracks   []CallTrack `json:"tracks,omitempty"`
+}
+
+// MessageSender reads the sender of an encoded Message without decoding the
+// rest of it; From is encoded near the start.
+func MessageSender(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return "", err
+	} else if tok != json.Delim('{') {
+		return "", ErrInvalidData
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		if key == "from" {
+			var from string
+			err := dec.Decode(&from)
+			return from, err
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
 }
 
 func (m *Message) ToJSON() []byte {
@@ -35,3 +201,50 @@ func (m *Message) ToJSON() []byte {
 func (m *Message) FromJson(data []byte) error {
 	return json.Unmarshal(data, m)
 }
+
+// WebhookSubscription sends the listed events (all when empty) to URL. An
+// empty RoomID subscribes to every room of the deployment.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	RoomID    string    `json:"room_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Bot answers the listed slash commands through an HTTP callback. An empty
+// RoomID makes the commands available in every room.
+type Bot struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Commands  []string  `json:"commands"`
+	RoomID    string    `json:"room_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	FailedAt       time.Time       `json:"failed_at,omitzero"`
+}
+
+type Recording struct {
+	EgressID  string    `json:"egress_id"`
+	RoomID    string    `json:"room_id"`
+	Status    string    `json:"status"`
+	StartedBy string    `json:"started_by,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at,omitempty"`
+	Filename  string    `json:"filename,omitempty"`
+	Location  string    `json:"location,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Duration  int64     `json:"duration,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
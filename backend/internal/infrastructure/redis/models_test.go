@@ -0,0 +1,30 @@
+package redisrepo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageSender(t *testing.T) {
+	msg := &Message{
+		Type:      "chat",
+		From:      "client-1",
+		RoomID:    "room-1",
+		Content:   `tricky "from":"someone-else"`,
+		Data:      map[string]any{"from": "nested"},
+		Timestamp: time.Now(),
+	}
+	from, err := MessageSender(msg.ToJSON())
+	if err != nil || from != "client-1" {
+		t.Fatalf("MessageSender = %q, %v; want client-1", from, err)
+	}
+
+	from, err = MessageSender([]byte(`{"data":{"from":"nested"},"type":"chat","from":"late"}`))
+	if err != nil || from != "late" {
+		t.Fatalf("MessageSender = %q, %v; want late", from, err)
+	}
+
+	if _, err := MessageSender([]byte(`["not", "a", "message"]`)); err == nil {
+		t.Fatal("MessageSender accepted a non-object payload")
+	}
+}
@@ -0,0 +1,123 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const archiveGroup = "archivers"
+
+// QueuedMessage is a message read from the archive queue or search stream.
+// ID is its stream entry ID, unique per message, so consumers can drop
+// redeliveries.
+type QueuedMessage struct {
+	ID      string
+	Message *Message
+}
+
+// EnableArchiveQueue makes SaveMessage queue every message for the archive,
+// keeping roughly the last maxLen when the archivers fall behind.
+func (r *RedisRepo) EnableArchiveQueue(ctx context.Context, maxLen int64) error {
+	err := r.db.XGroupCreateMkStream(ctx, r.keys.ArchiveQueueKey(), archiveGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	r.archiveQueue.Store(maxLen)
+	return nil
+}
+
+// ArchiveQueueLength returns how many messages wait to be archived.
+func (r *RedisRepo) ArchiveQueueLength(ctx context.Context) (int64, error) {
+	return r.db.XLen(ctx, r.keys.ArchiveQueueKey()).Result()
+}
+
+// ClaimArchiveBatch hands the consumer up to count queued messages. Entries
+// another consumer claimed but has not acknowledged for minIdle come first,
+// so messages held by a crashed node are not lost.
+func (r *RedisRepo) ClaimArchiveBatch(ctx context.Context, consumer string, count int, minIdle time.Duration) ([]*QueuedMessage, error) {
+	entries, _, err := r.db.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   r.keys.ArchiveQueueKey(),
+		Group:    archiveGroup,
+		Consumer: consumer,
+		MinIdle:  minIdle,
+		Start:    "0",
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) < count {
+		streams, err := r.db.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    archiveGroup,
+			Consumer: consumer,
+			Streams:  []string{r.keys.ArchiveQueueKey(), ">"},
+			Count:    int64(count - len(entries)),
+			Block:    -1,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for _, stream := range streams {
+			entries = append(entries, stream.Messages...)
+		}
+	}
+
+	queued := make([]*QueuedMessage, 0, len(entries))
+	var invalid []string
+	for _, entry := range entries {
+		data, _ := entry.Values["message"].(string)
+		var msg Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			invalid = append(invalid, entry.ID)
+			continue
+		}
+		queued = append(queued, &QueuedMessage{ID: entry.ID, Message: &msg})
+	}
+	if len(invalid) > 0 {
+		if err := r.AckArchived(ctx, invalid...); err != nil {
+			return nil, err
+		}
+	}
+	return queued, nil
+}
+
+// AckArchived removes archived messages from the queue.
+func (r *RedisRepo) AckArchived(ctx context.Context, ids ...string) error {
+	pipe := r.db.TxPipeline()
+	pipe.XAck(ctx, r.keys.ArchiveQueueKey(), archiveGroup, ids...)
+	pipe.XDel(ctx, r.keys.ArchiveQueueKey(), ids...)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ClaimArchivePurge reports whether this node should run the purge job, at
+// most once per interval across the cluster.
+func (r *RedisRepo) ClaimArchivePurge(ctx context.Context, interval time.Duration) (bool, error) {
+	return r.db.SetNX(ctx, r.keys.ArchivePurgeKey(), "1", interval).Result()
+}
+
+// TrimHistory drops the room's Redis history sent before the cutoff. Old
+// messages sit at the tail, so messages pushed meanwhile are not touched.
+func (r *RedisRepo) TrimHistory(ctx context.Context, roomID string, before time.Time) error {
+	key := r.keys.RoomMessagesKey(roomID)
+	data, err := r.db.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keep := len(data)
+	for keep > 0 {
+		var msg Message
+		if err := json.Unmarshal([]byte(data[keep-1]), &msg); err == nil && !msg.Timestamp.Before(before) {
+			break
+		}
+		keep--
+	}
+	if keep == len(data) {
+		return nil
+	}
+	return r.db.LTrim(ctx, key, 0, int64(keep-len(data)-1)).Err()
+}
@@ -0,0 +1,57 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+)
+
+// SaveBot stores the bot. Unless replace is set it fails with ErrBotExists
+// when the name is taken.
+func (r *RedisRepo) SaveBot(ctx context.Context, bot *Bot, replace bool) error {
+	data, err := json.Marshal(bot)
+	if err != nil {
+		return err
+	}
+	if replace {
+		return r.db.HSet(ctx, r.keys.BotsKey(), bot.Name, data).Err()
+	}
+	created, err := r.db.HSetNX(ctx, r.keys.BotsKey(), bot.Name, data).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrBotExists
+	}
+	return nil
+}
+
+func (r *RedisRepo) DeleteBot(ctx context.Context, name string) error {
+	removed, err := r.db.HDel(ctx, r.keys.BotsKey(), name).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+// GetBots returns all registered bots, oldest first.
+func (r *RedisRepo) GetBots(ctx context.Context) ([]*Bot, error) {
+	data, err := r.db.HGetAll(ctx, r.keys.BotsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	bots := make([]*Bot, 0, len(data))
+	for _, item := range data {
+		var bot Bot
+		if err := json.Unmarshal([]byte(item), &bot); err == nil {
+			bots = append(bots, &bot)
+		}
+	}
+	sort.Slice(bots, func(i, j int) bool {
+		return bots[i].CreatedAt.Before(bots[j].CreatedAt)
+	})
+	return bots, nil
+}
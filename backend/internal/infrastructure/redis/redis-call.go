@@ -0,0 +1,122 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KEYS: room call, room meta
+// ARGV: identity, participant JSON
+var setParticipantScript = redis.NewScript(`
+local p = cjson.decode(ARGV[2])
+local raw = redis.call('HGET', KEYS[1], ARGV[1])
+if raw and p.tracks == nil then
+  local old = cjson.decode(raw)
+  p.tracks = old.tracks
+end
+redis.call('HSET', KEYS[1], ARGV[1], cjson.encode(p))
+redis.call('HSET', KEYS[2], 'call_active', 1)
+return 1
+`)
+
+// KEYS: room call, room meta
+// ARGV: identity, track JSON, joined at for a participant not seen yet
+var addTrackScript = redis.NewScript(`
+local track = cjson.decode(ARGV[2])
+local raw = redis.call('HGET', KEYS[1], ARGV[1])
+local p = {identity = ARGV[1], joined_at = ARGV[3]}
+if raw then p = cjson.decode(raw) end
+local tracks = p.tracks
+if type(tracks) ~= 'table' then tracks = {} end
+local replaced = false
+for i, t in ipairs(tracks) do
+  if t.sid == track.sid then
+    tracks[i] = track
+    replaced = true
+    break
+  end
+end
+if not replaced then table.insert(tracks, track) end
+p.tracks = tracks
+redis.call('HSET', KEYS[1], ARGV[1], cjson.encode(p))
+redis.call('HSET', KEYS[2], 'call_active', 1)
+return 1
+`)
+
+func (r *RedisRepo) StartCall(ctx context.Context, roomID string) error {
+	pipe := r.db.Pipeline()
+	pipe.HSet(ctx, r.keys.RoomMetaKey(roomID), "call_active", 1)
+	pipe.HSet(ctx, r.keys.RoomMetaKey(roomID), "call_started_at", time.Now().Unix())
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisRepo) FinishCall(ctx context.Context, roomID string) error {
+	pipe := r.db.Pipeline()
+	pipe.HSet(ctx, r.keys.RoomMetaKey(roomID), "call_active", 0)
+	pipe.HSet(ctx, r.keys.RoomMetaKey(roomID), "call_finished_at", time.Now().Unix())
+	pipe.Del(ctx, r.keys.RoomCallKey(roomID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SetCallParticipant records a participant, keeping the tracks already
+// stored for it: LiveKit may report a track before the join.
+func (r *RedisRepo) SetCallParticipant(ctx context.Context, roomID string, p *CallParticipant) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	keys := []string{r.keys.RoomCallKey(roomID), r.keys.RoomMetaKey(roomID)}
+	return setParticipantScript.Run(ctx, r.db, keys, p.Identity, data).Err()
+}
+
+func (r *RedisRepo) GetCallParticipant(ctx context.Context, roomID, identity string) (*CallParticipant, error) {
+	data, err := r.db.HGet(ctx, r.keys.RoomCallKey(roomID), identity).Result()
+	if err == redis.Nil {
+		return nil, ErrClientNotInRoom
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p CallParticipant
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, ErrInvalidData
+	}
+	return &p, nil
+}
+
+func (r *RedisRepo) RemoveCallParticipant(ctx context.Context, roomID, identity string) error {
+	return r.db.HDel(ctx, r.keys.RoomCallKey(roomID), identity).Err()
+}
+
+// AddCallTrack adds or replaces one track of a participant. LiveKit reports
+// audio and video tracks on concurrent webhooks, so the update runs in Redis.
+func (r *RedisRepo) AddCallTrack(ctx context.Context, roomID, identity string, track CallTrack) error {
+	data, err := json.Marshal(track)
+	if err != nil {
+		return err
+	}
+	joined, _ := time.Now().MarshalJSON()
+	keys := []string{r.keys.RoomCallKey(roomID), r.keys.RoomMetaKey(roomID)}
+	return addTrackScript.Run(ctx, r.db, keys, identity, data, strings.Trim(string(joined), `"`)).Err()
+}
+
+func (r *RedisRepo) GetCallParticipants(ctx context.Context, roomID string) ([]*CallParticipant, error) {
+	data, err := r.db.HGetAll(ctx, r.keys.RoomCallKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	participants := make([]*CallParticipant, 0, len(data))
+	for _, item := range data {
+		var p CallParticipant
+		if err := json.Unmarshal([]byte(item), &p); err == nil {
+			participants = append(participants, &p)
+		}
+	}
+	return participants, nil
+}
@@ -0,0 +1,200 @@
+package redisrepo
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The directory indexes public rooms in two sorted sets: room IDs scored by
+// last activity, and "<lowercased name>\x00<room ID>" members at score 0 so
+// ZRANGEBYLEX can answer prefix searches without scanning rooms. A listed
+// room keeps its name entry in the directory_name meta field, so closing the
+// room removes exactly that entry.
+const (
+	defaultDirectoryPage = 20
+	maxDirectoryPage     = 100
+	// directorySearchWindow caps how many name matches, taken in name
+	// order, are ranked by activity for a prefix search. Results say when
+	// more matched.
+	directorySearchWindow = 500
+)
+
+type DirectorySort string
+
+const (
+	DirectoryByActivity DirectorySort = "activity"
+	DirectoryByName     DirectorySort = "name"
+)
+
+// DirectoryResult is a page of the directory. More is set when rooms follow
+// the page. Truncated is set when a prefix search by activity matched more
+// than directorySearchWindow rooms and only that many, the first by name,
+// were ranked.
+type DirectoryResult struct {
+	Rooms     []*DirectoryEntry
+	More      bool
+	Truncated bool
+}
+
+type DirectoryQuery struct {
+	Prefix string
+	Sort   DirectorySort
+	Offset int
+	Limit  int
+}
+
+// Normalize fills in defaults and clamps the page size.
+func (q *DirectoryQuery) Normalize() {
+	q.Prefix = strings.ToLower(strings.TrimSpace(q.Prefix))
+	if q.Sort != DirectoryByName {
+		q.Sort = DirectoryByActivity
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultDirectoryPage
+	}
+	q.Limit = min(q.Limit, maxDirectoryPage)
+}
+
+func directoryName(roomID string, info *RoomInfo) string {
+	name := info.Name
+	if name == "" {
+		name = roomID
+	}
+	return strings.ToLower(name) + "\x00" + roomID
+}
+
+// indexRoom queues the directory update for a room whose info changed.
+func (r *RedisRepo) indexRoom(ctx context.Context, pipe redis.Pipeliner, roomID string, previous, info *RoomInfo) {
+	pipe.ZRem(ctx, r.keys.DirectoryNamesKey(), directoryName(roomID, previous))
+	if !info.Public {
+		pipe.ZRem(ctx, r.keys.DirectoryActivityKey(), roomID)
+		pipe.HDel(ctx, r.keys.RoomMetaKey(roomID), "directory_name")
+		return
+	}
+	name := directoryName(roomID, info)
+	pipe.ZAddNX(ctx, r.keys.DirectoryActivityKey(), redis.Z{Score: float64(time.Now().Unix()), Member: roomID})
+	pipe.ZAdd(ctx, r.keys.DirectoryNamesKey(), redis.Z{Score: 0, Member: name})
+	pipe.HSet(ctx, r.keys.RoomMetaKey(roomID), "directory_name", name)
+}
+
+// unlistRoom queues the removal of the room from the directory.
+func (r *RedisRepo) unlistRoom(ctx context.Context, pipe redis.Pipeliner, roomID, name string) {
+	if name != "" {
+		pipe.ZRem(ctx, r.keys.DirectoryNamesKey(), name)
+	}
+	pipe.ZRem(ctx, r.keys.DirectoryActivityKey(), roomID)
+}
+
+// touchRoom bumps the activity of a listed room; unlisted rooms are left out.
+func (r *RedisRepo) touchRoom(ctx context.Context, pipe redis.Pipeliner, roomID string) {
+	pipe.ZAddXX(ctx, r.keys.DirectoryActivityKey(), redis.Z{Score: float64(time.Now().Unix()), Member: roomID})
+}
+
+// ListRooms returns a page of public rooms.
+func (r *RedisRepo) ListRooms(ctx context.Context, q DirectoryQuery) (*DirectoryResult, error) {
+	q.Normalize()
+	if q.Prefix == "" && q.Sort == DirectoryByActivity {
+		ids, err := r.db.ZRevRange(ctx, r.keys.DirectoryActivityKey(), int64(q.Offset), int64(q.Offset+q.Limit)).Result()
+		if err != nil {
+			return nil, err
+		}
+		rooms, more, err := r.directoryPage(ctx, ids, q.Limit)
+		return &DirectoryResult{Rooms: rooms, More: more}, err
+	}
+
+	rng := &redis.ZRangeBy{Min: "-", Max: "+", Offset: int64(q.Offset), Count: int64(q.Limit + 1)}
+	if q.Prefix != "" {
+		rng.Min, rng.Max = "["+q.Prefix, "["+q.Prefix+"\xff"
+	}
+	if q.Sort == DirectoryByActivity {
+		rng.Offset, rng.Count = 0, directorySearchWindow+1
+	}
+	members, err := r.db.ZRangeByLex(ctx, r.keys.DirectoryNamesKey(), rng).Result()
+	if err != nil {
+		return nil, err
+	}
+	truncated := q.Sort == DirectoryByActivity && len(members) > directorySearchWindow
+	if truncated {
+		members = members[:directorySearchWindow]
+	}
+	ids := make([]string, 0, len(members))
+	for _, m := range members {
+		if _, id, ok := strings.Cut(m, "\x00"); ok {
+			ids = append(ids, id)
+		}
+	}
+	if q.Sort == DirectoryByName {
+		rooms, more, err := r.directoryPage(ctx, ids, q.Limit)
+		return &DirectoryResult{Rooms: rooms, More: more}, err
+	}
+
+	entries, _, err := r.directoryPage(ctx, ids, len(ids))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastActivity.After(entries[j].LastActivity)
+	})
+	res := &DirectoryResult{Truncated: truncated}
+	if q.Offset >= len(entries) {
+		return res, nil
+	}
+	res.Rooms = entries[q.Offset:]
+	if len(res.Rooms) > q.Limit {
+		res.Rooms, res.More = res.Rooms[:q.Limit], true
+	}
+	return res, nil
+}
+
+// directoryPage loads the listed rooms among ids, at most limit of them, and
+// reports whether more were available.
+func (r *RedisRepo) directoryPage(ctx context.Context, ids []string, limit int) ([]*DirectoryEntry, bool, error) {
+	if len(ids) == 0 {
+		return nil, false, nil
+	}
+	pipe := r.db.Pipeline()
+	metas := make([]*redis.SliceCmd, len(ids))
+	counts := make([]*redis.IntCmd, len(ids))
+	scores := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		metas[i] = pipe.HMGet(ctx, r.keys.RoomMetaKey(id), "name", "topic", "avatar_url")
+		counts[i] = pipe.SCard(ctx, r.keys.RoomClientsKey(id))
+		scores[i] = pipe.ZScore(ctx, r.keys.DirectoryActivityKey(), id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, false, err
+	}
+
+	entries := make([]*DirectoryEntry, 0, min(len(ids), limit))
+	for i, id := range ids {
+		score, err := scores[i].Result()
+		if err == redis.Nil {
+			continue // unlisted between the two reads
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if len(entries) == limit {
+			return entries, true, nil
+		}
+		entry := &DirectoryEntry{
+			RoomID:       id,
+			Members:      counts[i].Val(),
+			LastActivity: time.Unix(int64(score), 0).UTC(),
+		}
+		if values := metas[i].Val(); len(values) == 3 {
+			entry.Name, _ = values[0].(string)
+			entry.Topic, _ = values[1].(string)
+			entry.AvatarURL, _ = values[2].(string)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, false, nil
+}
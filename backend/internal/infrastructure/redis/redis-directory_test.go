@@ -0,0 +1,85 @@
+package redisrepo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPrefixSearchByActivityReportsTruncation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	public := true
+	for i := range directorySearchWindow + 1 {
+		name := fmt.Sprintf("chess %03d", i)
+		if _, err := repo.UpdateRoomInfo(ctx, fmt.Sprintf("room-%d", i), RoomInfoUpdate{Name: &name, Public: &public}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := repo.ListRooms(ctx, DirectoryQuery{Prefix: "chess", Sort: DirectoryByActivity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Truncated || !res.More || len(res.Rooms) != defaultDirectoryPage {
+		t.Fatalf("got truncated=%v more=%v rooms=%d", res.Truncated, res.More, len(res.Rooms))
+	}
+
+	res, err = repo.ListRooms(ctx, DirectoryQuery{Prefix: "chess 1", Sort: DirectoryByActivity})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Truncated {
+		t.Fatal("a search that fits the window was reported as truncated")
+	}
+
+	res, err = repo.ListRooms(ctx, DirectoryQuery{Prefix: "chess", Sort: DirectoryByName, Offset: directorySearchWindow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Truncated || len(res.Rooms) != 1 || res.Rooms[0].Name != "chess 500" {
+		t.Fatalf("sorting by name should reach every match, got %+v", res)
+	}
+}
+
+func TestClosedRoomsLeaveTheNameIndex(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	public := true
+	for i, name := range []string{"Шахматы", "Chess", "Go"} {
+		roomID := fmt.Sprintf("room-%d", i)
+		if _, err := repo.JoinRoom(ctx, &ClientInfo{ID: roomID + "-host", RoomID: roomID, JoinedAt: time.Now()}, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.UpdateRoomInfo(ctx, roomID, RoomInfoUpdate{Name: &name, Public: &public}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.RemoveClient(ctx, "room-0-host"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ClearRoom(ctx, "room-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	names := db.ZRange(ctx, repo.keys.DirectoryNamesKey(), 0, -1).Val()
+	if len(names) != 1 || names[0] != directoryName("room-2", &RoomInfo{Name: "Go"}) {
+		t.Fatalf("name index %q, want only the open room", names)
+	}
+	res, err := repo.ListRooms(ctx, DirectoryQuery{Sort: DirectoryByName})
+	if err != nil || len(res.Rooms) != 1 || res.Rooms[0].RoomID != "room-2" {
+		t.Fatalf("ListRooms = %+v, %v", res, err)
+	}
+}
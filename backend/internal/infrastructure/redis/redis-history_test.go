@@ -0,0 +1,83 @@
+package redisrepo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPersistentHistoryPagesPastFirstPage(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	if err := repo.SetRoomLifecycle(ctx, "room-1", LifecyclePersistent); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	total := historyLimit * 3
+	for i := range total {
+		msg := &Message{Type: "chat", RoomID: "room-1", Content: fmt.Sprint(i), Timestamp: start.Add(time.Duration(i) * time.Second)}
+		if err := repo.SaveMessage(ctx, "room-1", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// older than everything on the first page of the list
+	before := start.Add(time.Duration(total-historyPage-10) * time.Second)
+	messages, err := repo.GetMessagesBefore(ctx, "room-1", before, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 5 || messages[0].Content != fmt.Sprint(total-historyPage-11) {
+		t.Fatalf("got %d messages starting at %v", len(messages), messages)
+	}
+
+	oldest, err := repo.GetMessagesBefore(ctx, "room-1", start.Add(time.Second), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldest) != 1 || oldest[0].Content != "0" {
+		t.Fatalf("persistent room lost its oldest message: %v", oldest)
+	}
+}
+
+func TestEphemeralHistoryIsCapped(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	for i := range historyLimit + 20 {
+		repo.SaveMessage(ctx, "room-1", &Message{Type: "chat", Content: fmt.Sprint(i), Timestamp: time.Now()})
+	}
+	if n, _ := db.LLen(ctx, repo.keys.RoomMessagesKey("room-1")).Result(); n != historyLimit {
+		t.Fatalf("kept %d messages, want %d", n, historyLimit)
+	}
+}
+
+func TestArchiveQueueIsCapped(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	if err := repo.EnableArchiveQueue(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 25 {
+		repo.SaveMessage(ctx, "room-1", &Message{Type: "chat", Content: fmt.Sprint(i), Timestamp: time.Now()})
+	}
+	// real Redis trims approximately; miniredis trims to the cap
+	if n, err := repo.ArchiveQueueLength(ctx); err != nil || n != 10 {
+		t.Fatalf("ArchiveQueueLength = %d, %v; want 10", n, err)
+	}
+}
@@ -1,6 +1,9 @@
 package redisrepo
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Keys struct{}
 
@@ -24,14 +27,101 @@ func (k *Keys) RoomMessagesKey(roomID string) string {
 	return fmt.Sprintf("room:%s:messages", roomID)
 }
 
+func (k *Keys) RoomRolesKey(roomID string) string {
+	return fmt.Sprintf("room:%s:roles", roomID)
+}
+
+// RoomMemberRolesKey holds the roles a persistent room keeps for members who
+// left, by member ID; only roles other than the room's default are kept.
+func (k *Keys) RoomMemberRolesKey(roomID string) string {
+	return fmt.Sprintf("room:%s:member_roles", roomID)
+}
+
+// RoomJoinOrderKey ranks the room's members by when they entered, so the
+// host role passes to whoever has been there longest.
+func (k *Keys) RoomJoinOrderKey(roomID string) string {
+	return fmt.Sprintf("room:%s:joined", roomID)
+}
+
+func (k *Keys) RoomCallKey(roomID string) string {
+	return fmt.Sprintf("room:%s:call", roomID)
+}
+
+func (k *Keys) RoomRecordingsKey(roomID string) string {
+	return fmt.Sprintf("room:%s:recordings", roomID)
+}
+
+func (k *Keys) RoomLobbyKey(roomID string) string {
+	return fmt.Sprintf("room:%s:lobby", roomID)
+}
+
+func (k *Keys) RoomLobbyDecisionsKey(roomID string) string {
+	return fmt.Sprintf("room:%s:lobby:decisions", roomID)
+}
+
+// RoomLobbyTicketsKey holds the tickets of the room's waiting clients, each
+// an expiry in ms and the client's name.
+func (k *Keys) RoomLobbyTicketsKey(roomID string) string {
+	return fmt.Sprintf("room:%s:lobby:tickets", roomID)
+}
+
 func (k *Keys) RoomChannel(roomID string) string {
 	return fmt.Sprintf("room:%s", roomID)
 }
 
+// ChannelRoom is the inverse of RoomChannel.
+func (k *Keys) ChannelRoom(channel string) string {
+	return strings.TrimPrefix(channel, "room:")
+}
+
+func (k *Keys) WebhookSubscriptionsKey() string {
+	return "webhooks:subscriptions"
+}
+
+func (k *Keys) WebhookDeliveriesKey() string {
+	return "webhooks:deliveries"
+}
+
+func (k *Keys) WebhookQueueKey() string {
+	return "webhooks:queue"
+}
+
+func (k *Keys) WebhookDeadLetterKey() string {
+	return "webhooks:dead"
+}
+
+func (k *Keys) ArchiveQueueKey() string {
+	return "archive:queue"
+}
+
+func (k *Keys) ArchivePurgeKey() string {
+	return "archive:purge"
+}
+
+func (k *Keys) SearchStreamKey() string {
+	return "search:stream"
+}
+
+func (k *Keys) BotsKey() string {
+	return "bots"
+}
+
 func (k *Keys) ActiveRoomsKey() string {
 	return "rooms:active"
 }
 
+func (k *Keys) PersistentRoomsKey() string {
+	return "rooms:persistent"
+}
+
+func (k *Keys) DirectoryActivityKey() string {
+	return "rooms:directory:activity"
+}
+
+func (k *Keys) DirectoryNamesKey() string {
+	return "rooms:directory:names"
+}
+
 func (k *Keys) AllRoomsPattern() string {
 	return "room:*"
 }
@@ -0,0 +1,57 @@
+package redisrepo
+
+import (
+	"context"
+	"time"
+)
+
+// Redis keeps the latest historyLimit messages of an ephemeral room for
+// historyTTL after its last message, and the latest persistentHistoryLimit
+// messages of a persistent room for as long as it exists. Older messages are
+// only in the archive, when one is configured. History is read historyPage
+// messages at a time.
+const (
+	historyTTL             = 24 * time.Hour
+	historyLimit           = 100
+	persistentHistoryLimit = 1000
+	historyPage            = 100
+)
+
+func (r *RedisRepo) GetRoomLifecycle(ctx context.Context, roomID string) (Lifecycle, error) {
+	persistent, err := r.db.SIsMember(ctx, r.keys.PersistentRoomsKey(), roomID).Result()
+	if err != nil {
+		return "", err
+	}
+	if persistent {
+		return LifecyclePersistent, nil
+	}
+	return LifecycleEphemeral, nil
+}
+
+// SetRoomLifecycle switches a room between ephemeral and persistent. Making a
+// room persistent creates it when it does not exist yet, so standing rooms can
+// be set up before anyone joins; making an empty room ephemeral closes it.
+// Persistent rooms keep a longer history and their members' roles.
+func (r *RedisRepo) SetRoomLifecycle(ctx context.Context, roomID string, lifecycle Lifecycle) error {
+	pipe := r.db.TxPipeline()
+	switch lifecycle {
+	case LifecyclePersistent:
+		pipe.SAdd(ctx, r.keys.PersistentRoomsKey(), roomID)
+		pipe.SAdd(ctx, r.keys.ActiveRoomsKey(), roomID)
+		pipe.HSetNX(ctx, r.keys.RoomMetaKey(roomID), "created_at", time.Now().Unix())
+		pipe.HSetNX(ctx, r.keys.RoomMetaKey(roomID), "last_seen", time.Now().Unix())
+		pipe.Persist(ctx, r.keys.RoomMessagesKey(roomID))
+	case LifecycleEphemeral:
+		pipe.SRem(ctx, r.keys.PersistentRoomsKey(), roomID)
+		pipe.Expire(ctx, r.keys.RoomMessagesKey(roomID), historyTTL)
+	default:
+		return ErrInvalidData
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	if lifecycle == LifecycleEphemeral {
+		return r.closeIfEmpty(ctx, roomID)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+package redisrepo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// lobbyTicketTTL is how long a waiting client stays queued without its node
+// refreshing the ticket, so clients of a crashed node do not block the queue.
+const lobbyTicketTTL = 15 * time.Second
+
+// roomGate is shared by the join and admit scripts. It drops expired entries
+// from the head of the lobby, where they would hold up the queue, and works
+// out the room's capacity. Tickets live in a hash per room, "<expiry ms>|<name>"
+// by client ID, so the scripts only touch keys they declare.
+//
+// KEYS: room clients, room meta, lobby, active rooms, directory activity,
+// lobby tickets, lobby decisions, room join order
+// ARGV: client ID, room ID, default capacity, now (s), now (ms), name,
+// ticket TTL (ms)
+const roomGate = `
+local id, room = ARGV[1], ARGV[2]
+local now = tonumber(ARGV[5])
+while true do
+  local head = redis.call('ZRANGE', KEYS[3], 0, 0)[1]
+  if not head or head == id then
+    break
+  end
+  local ticket = redis.call('HGET', KEYS[6], head)
+  local expiry = ticket and tonumber(string.match(ticket, '^(%d+)|'))
+  if expiry and expiry > now then
+    break
+  end
+  redis.call('ZREM', KEYS[3], head)
+  redis.call('HDEL', KEYS[6], head)
+  redis.call('HDEL', KEYS[7], head)
+end
+local count = redis.call('SCARD', KEYS[1])
+local cap = tonumber(redis.call('HGET', KEYS[2], 'max_members') or '0')
+if cap == 0 then cap = tonumber(ARGV[3]) end
+local free = 2147483647
+if cap > 0 then free = cap - count end
+local knock = count > 0 and redis.call('HGET', KEYS[2], 'knock') == '1'
+
+local function enter()
+  redis.call('ZREM', KEYS[3], id)
+  redis.call('HDEL', KEYS[7], id)
+  redis.call('HDEL', KEYS[6], id)
+  redis.call('SADD', KEYS[1], id)
+  redis.call('ZADD', KEYS[8], 'NX', ARGV[5], id)
+  redis.call('HSET', KEYS[2], 'last_seen', ARGV[4])
+  local created = redis.call('HSETNX', KEYS[2], 'created_at', ARGV[4])
+  redis.call('SADD', KEYS[4], room)
+  redis.call('ZADD', KEYS[5], 'XX', ARGV[4], room)
+  return {1, created, 0}
+end
+
+local function wait()
+  redis.call('ZADD', KEYS[3], 'NX', ARGV[5], id)
+  redis.call('HSET', KEYS[6], id, (now + tonumber(ARGV[7])) .. '|' .. ARGV[6])
+  redis.call('PEXPIRE', KEYS[6], ARGV[7])
+  return {0, 0, redis.call('ZRANK', KEYS[3], id) + 1}
+end
+`
+
+// joinScript lets the client in when the room has room and nobody is queued,
+// and queues it in the lobby otherwise.
+var joinScript = redis.NewScript(roomGate + `
+if redis.call('SISMEMBER', KEYS[1], id) == 1 then
+  return {1, 0, 0}
+end
+if knock or free <= 0 or redis.call('ZCARD', KEYS[3]) > 0 then
+  return wait()
+end
+return enter()
+`)
+
+// admitScript re-checks a queued client: it enters when a moderator admitted
+// it or, outside knock mode, when its place in the queue fits the free slots.
+// A client that was denied or dropped from the lobby gets position 0.
+var admitScript = redis.NewScript(roomGate + `
+local decision = redis.call('HGET', KEYS[7], id)
+if decision == 'deny' or not redis.call('ZSCORE', KEYS[3], id) then
+  redis.call('ZREM', KEYS[3], id)
+  redis.call('HDEL', KEYS[7], id)
+  redis.call('HDEL', KEYS[6], id)
+  return {0, 0, 0}
+end
+local rank = redis.call('ZRANK', KEYS[3], id)
+if (decision == 'admit' and free > 0) or (not knock and rank < free) then
+  return enter()
+end
+return wait()
+`)
+
+// closeScript cleans up a room once its last member is gone. Persistent
+// rooms only drop the host claim, so the next member to join becomes host;
+// other rooms also leave the directory.
+//
+// KEYS: room clients, room call, room meta, room roles, active rooms,
+// directory activity, persistent rooms, room join order, room member roles,
+// directory names
+// ARGV: room ID
+var closeScript = redis.NewScript(`
+if redis.call('SCARD', KEYS[1]) > 0 then
+  return 0
+end
+redis.call('DEL', KEYS[1], KEYS[2], KEYS[8])
+if redis.call('SISMEMBER', KEYS[7], ARGV[1]) == 1 then
+  redis.call('HDEL', KEYS[3], 'owner', 'call_active')
+else
+  local listed = redis.call('HGET', KEYS[3], 'directory_name')
+  if listed then
+    redis.call('ZREM', KEYS[10], listed)
+  end
+  redis.call('DEL', KEYS[3], KEYS[4], KEYS[9])
+  redis.call('SREM', KEYS[5], ARGV[1])
+  redis.call('ZREM', KEYS[6], ARGV[1])
+end
+return 1
+`)
+
+// JoinResult tells whether a client entered its room or waits in the lobby
+// at Position (1 is next). Position 0 outside the room means the client was
+// denied or dropped from the lobby.
+type JoinResult struct {
+	Admitted bool
+	Created  bool
+	Position int64
+}
+
+// JoinRoom lets the client into its room, or queues it in the lobby when the
+// room is full, knock-to-enter or already has a queue. maxMembers applies to
+// rooms without their own limit; 0 means unlimited.
+func (r *RedisRepo) JoinRoom(ctx context.Context, info *ClientInfo, maxMembers int) (*JoinResult, error) {
+	return r.runGate(ctx, joinScript, info, maxMembers)
+}
+
+// AdmitFromLobby re-checks a queued client and lets it in when its turn has
+// come. It also keeps the client's lobby ticket alive, so nodes call it
+// periodically for every client they have waiting.
+func (r *RedisRepo) AdmitFromLobby(ctx context.Context, info *ClientInfo, maxMembers int) (*JoinResult, error) {
+	return r.runGate(ctx, admitScript, info, maxMembers)
+}
+
+func (r *RedisRepo) runGate(ctx context.Context, script *redis.Script, info *ClientInfo, maxMembers int) (*JoinResult, error) {
+	now := time.Now()
+	keys := []string{
+		r.keys.RoomClientsKey(info.RoomID),
+		r.keys.RoomMetaKey(info.RoomID),
+		r.keys.RoomLobbyKey(info.RoomID),
+		r.keys.ActiveRoomsKey(),
+		r.keys.DirectoryActivityKey(),
+		r.keys.RoomLobbyTicketsKey(info.RoomID),
+		r.keys.RoomLobbyDecisionsKey(info.RoomID),
+		r.keys.RoomJoinOrderKey(info.RoomID),
+	}
+	values, err := script.Run(ctx, r.db, keys,
+		info.ID, info.RoomID, maxMembers, now.Unix(), now.UnixMilli(),
+		info.Name, lobbyTicketTTL.Milliseconds(),
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 3 {
+		return nil, ErrInvalidData
+	}
+	res := &JoinResult{Admitted: values[0] == 1, Created: values[1] == 1, Position: values[2]}
+	if res.Admitted {
+		if err := r.saveClient(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (r *RedisRepo) saveClient(ctx context.Context, info *ClientInfo) error {
+	pipe := r.db.Pipeline()
+	pipe.Set(ctx, r.keys.ClientKey(info.ID), info.RoomID, 24*time.Hour)
+	pipe.HSet(ctx, r.keys.ClientMetaKey(info.ID), map[string]any{
+		"joined_at":  info.JoinedAt.Unix(),
+		"user_agent": info.UserAgent,
+		"name":       info.Name,
+		"member":     info.Member,
+	})
+	pipe.Expire(ctx, r.keys.ClientMetaKey(info.ID), 24*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LeaveLobby removes a waiting client that disconnected.
+func (r *RedisRepo) LeaveLobby(ctx context.Context, roomID, clientID string) error {
+	pipe := r.db.TxPipeline()
+	pipe.ZRem(ctx, r.keys.RoomLobbyKey(roomID), clientID)
+	pipe.HDel(ctx, r.keys.RoomLobbyDecisionsKey(roomID), clientID)
+	pipe.HDel(ctx, r.keys.RoomLobbyTicketsKey(roomID), clientID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetLobby returns the clients waiting to enter the room, next first.
+func (r *RedisRepo) GetLobby(ctx context.Context, roomID string) ([]*LobbyEntry, error) {
+	queued, err := r.db.ZRangeWithScores(ctx, r.keys.RoomLobbyKey(roomID), 0, -1).Result()
+	if err != nil || len(queued) == 0 {
+		return []*LobbyEntry{}, err
+	}
+	tickets, err := r.db.HGetAll(ctx, r.keys.RoomLobbyTicketsKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	decisions, err := r.db.HGetAll(ctx, r.keys.RoomLobbyDecisionsKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*LobbyEntry, 0, len(queued))
+	now := time.Now().UnixMilli()
+	for _, z := range queued {
+		id := z.Member.(string)
+		expiry, name, ok := parseTicket(tickets[id])
+		if !ok || expiry <= now {
+			continue // ticket expired, dropped on the next admit check
+		}
+		entries = append(entries, &LobbyEntry{
+			ID:       id,
+			Name:     name,
+			Position: len(entries) + 1,
+			Since:    time.UnixMilli(int64(z.Score)).UTC(),
+			Admitted: decisions[id] == "admit",
+		})
+	}
+	return entries, nil
+}
+
+// parseTicket splits a lobby ticket into its expiry (ms) and client name.
+func parseTicket(ticket string) (int64, string, bool) {
+	expiry, name, ok := strings.Cut(ticket, "|")
+	if !ok {
+		return 0, "", false
+	}
+	ms, err := strconv.ParseInt(expiry, 10, 64)
+	return ms, name, err == nil
+}
+
+// DecideLobby records a moderator's decision for a waiting client. The node
+// holding the client applies it on its next admit check.
+func (r *RedisRepo) DecideLobby(ctx context.Context, roomID, clientID string, admit bool) error {
+	if err := r.db.ZScore(ctx, r.keys.RoomLobbyKey(roomID), clientID).Err(); err == redis.Nil {
+		return ErrNotInLobby
+	} else if err != nil {
+		return err
+	}
+	decision := "deny"
+	if admit {
+		decision = "admit"
+	}
+	return r.db.HSet(ctx, r.keys.RoomLobbyDecisionsKey(roomID), clientID, decision).Err()
+}
+
+// closeIfEmpty runs closeScript for the room.
+func (r *RedisRepo) closeIfEmpty(ctx context.Context, roomID string) error {
+	keys := []string{
+		r.keys.RoomClientsKey(roomID),
+		r.keys.RoomCallKey(roomID),
+		r.keys.RoomMetaKey(roomID),
+		r.keys.RoomRolesKey(roomID),
+		r.keys.ActiveRoomsKey(),
+		r.keys.DirectoryActivityKey(),
+		r.keys.PersistentRoomsKey(),
+		r.keys.RoomJoinOrderKey(roomID),
+		r.keys.RoomMemberRolesKey(roomID),
+		r.keys.DirectoryNamesKey(),
+	}
+	return closeScript.Run(ctx, r.db, keys, roomID).Err()
+}
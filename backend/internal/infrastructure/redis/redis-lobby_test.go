@@ -0,0 +1,62 @@
+package redisrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLobbyTicketsLiveInTheRoomHash(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+	info := func(id string) *ClientInfo {
+		return &ClientInfo{ID: id, RoomID: "room-1", Name: id, JoinedAt: time.Now()}
+	}
+
+	if res, err := repo.JoinRoom(ctx, info("alice"), 1); err != nil || !res.Admitted {
+		t.Fatalf("JoinRoom = %+v, %v; want alice admitted", res, err)
+	}
+	for i, id := range []string{"bob", "carol"} {
+		res, err := repo.JoinRoom(ctx, info(id), 1)
+		if err != nil || res.Admitted || res.Position != int64(i+1) {
+			t.Fatalf("JoinRoom(%s) = %+v, %v; want position %d", id, res, err, i+1)
+		}
+	}
+	tickets := repo.keys.RoomLobbyTicketsKey("room-1")
+	if n := db.HLen(ctx, tickets).Val(); n != 2 {
+		t.Fatalf("%d tickets, want 2", n)
+	}
+	entries, err := repo.GetLobby(ctx, "room-1")
+	if err != nil || len(entries) != 2 || entries[0].Name != "bob" || entries[1].Name != "carol" {
+		t.Fatalf("GetLobby = %+v, %v", entries, err)
+	}
+
+	// bob's node stopped refreshing his ticket
+	db.HSet(ctx, tickets, "bob", "1|bob")
+	if entries, _ := repo.GetLobby(ctx, "room-1"); len(entries) != 1 || entries[0].ID != "carol" {
+		t.Fatalf("GetLobby listed an expired ticket: %+v", entries)
+	}
+	res, err := repo.AdmitFromLobby(ctx, info("carol"), 1)
+	if err != nil || res.Admitted || res.Position != 1 {
+		t.Fatalf("AdmitFromLobby = %+v, %v; want carol first in line", res, err)
+	}
+	if db.HExists(ctx, tickets, "bob").Val() {
+		t.Fatal("expired ticket was not dropped from the head of the lobby")
+	}
+
+	if _, err := repo.RemoveClient(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := repo.AdmitFromLobby(ctx, info("carol"), 1); err != nil || !res.Admitted {
+		t.Fatalf("AdmitFromLobby = %+v, %v; want carol admitted", res, err)
+	}
+	if db.Exists(ctx, tickets).Val() != 0 {
+		t.Fatal("admitted client kept its ticket")
+	}
+}
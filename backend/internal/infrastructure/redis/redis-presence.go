@@ -0,0 +1,43 @@
+package redisrepo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// GetRoomMembers lists everyone connected to the room on any node, ordered by
+// join time.
+func (r *RedisRepo) GetRoomMembers(ctx context.Context, roomID string) ([]*RoomMember, error) {
+	ids, err := r.GetRoomClients(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	pipe := r.db.Pipeline()
+	metas := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		metas[i] = pipe.HGetAll(ctx, r.keys.ClientMetaKey(id))
+	}
+	roles := pipe.HGetAll(ctx, r.keys.RoomRolesKey(roomID))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	members := make([]*RoomMember, 0, len(ids))
+	for i, id := range ids {
+		meta := metas[i].Val()
+		role, _ := ParseRole(roles.Val()[id])
+		members = append(members, &RoomMember{
+			ID:       id,
+			Name:     meta["name"],
+			Role:     role,
+			JoinedAt: time.Unix(atol(meta["joined_at"]), 0),
+		})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].JoinedAt.Before(members[j].JoinedAt)
+	})
+	return members, nil
+}
@@ -0,0 +1,51 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func (r *RedisRepo) SaveRecording(ctx context.Context, rec *Recording) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return r.db.HSet(ctx, r.keys.RoomRecordingsKey(rec.RoomID), rec.EgressID, data).Err()
+}
+
+func (r *RedisRepo) GetRecording(ctx context.Context, roomID, egressID string) (*Recording, error) {
+	data, err := r.db.HGet(ctx, r.keys.RoomRecordingsKey(roomID), egressID).Result()
+	if err == redis.Nil {
+		return nil, ErrRecordingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, ErrInvalidData
+	}
+	return &rec, nil
+}
+
+// GetRecordings returns the room's recordings, newest first.
+func (r *RedisRepo) GetRecordings(ctx context.Context, roomID string) ([]*Recording, error) {
+	data, err := r.db.HGetAll(ctx, r.keys.RoomRecordingsKey(roomID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	recordings := make([]*Recording, 0, len(data))
+	for _, item := range data {
+		var rec Recording
+		if err := json.Unmarshal([]byte(item), &rec); err == nil {
+			recordings = append(recordings, &rec)
+		}
+	}
+	sort.Slice(recordings, func(i, j int) bool {
+		return recordings[i].StartedAt.After(recordings[j].StartedAt)
+	})
+	return recordings, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +13,9 @@ import (
 type RedisRepo struct {
 	db   *redis.Client
 	keys Keys
+
+	archiveQueue atomic.Int64
+	searchStream atomic.Int64
 }
 
 func NewRedisRepo(db *redis.Client) *RedisRepo {
@@ -21,43 +25,78 @@ func NewRedisRepo(db *redis.Client) *RedisRepo {
 	}
 }
 
-func (r *RedisRepo) AddClient(ctx context.Context, info *ClientInfo) error {
-	pipe := r.db.Pipeline()
-	pipe.Set(ctx, r.keys.ClientKey(info.ID), info.RoomID, 24*time.Hour)
-	pipe.SAdd(ctx, r.keys.RoomClientsKey(info.RoomID), info.ID)
-	pipe.HSet(ctx, r.keys.ClientMetaKey(info.ID), map[string]any{
-		"joined_at":  info.JoinedAt.Unix(),
-		"user_agent": info.UserAgent,
-	})
-	pipe.Expire(ctx, r.keys.ClientMetaKey(info.ID), 24*time.Hour)
-	pipe.HSet(ctx, r.keys.RoomMetaKey(info.RoomID), "last_seen", time.Now().Unix())
-	pipe.HSetNX(ctx, r.keys.RoomMetaKey(info.RoomID), "created_at", time.Now().Unix())
-	pipe.SAdd(ctx, r.keys.ActiveRoomsKey(), info.RoomID)
-	_, err := pipe.Exec(ctx)
-	return err
-}
-
-func (r *RedisRepo) RemoveClient(ctx context.Context, clientID string) error {
+// leaveScript removes a member. Persistent rooms keep its role under its
+// member ID when it differs from the room's default. When it was the room's
+// owner the claim passes to another host, or else the longest-present member
+// is made host, whose ID is returned.
+//
+// KEYS: room clients, room roles, room meta, room join order, client,
+// client meta, persistent rooms, room member roles
+// ARGV: client ID, room ID
+var leaveScript = redis.NewScript(`
+local id = ARGV[1]
+redis.call('SREM', KEYS[1], id)
+redis.call('ZREM', KEYS[4], id)
+local role = redis.call('HGET', KEYS[2], id)
+redis.call('HDEL', KEYS[2], id)
+local member = redis.call('HGET', KEYS[6], 'member')
+if role and member and member ~= '' and redis.call('SISMEMBER', KEYS[7], ARGV[2]) == 1 then
+  local default = redis.call('HGET', KEYS[3], 'default_role')
+  if not default or default == '' then default = 'participant' end
+  if role == default then
+    redis.call('HDEL', KEYS[8], member)
+  else
+    redis.call('HSET', KEYS[8], member, role)
+  end
+end
+redis.call('DEL', KEYS[5], KEYS[6])
+if redis.call('HGET', KEYS[3], 'owner') ~= id then
+  return ''
+end
+local members = redis.call('ZRANGE', KEYS[4], 0, -1)
+for _, m in ipairs(members) do
+  if redis.call('HGET', KEYS[2], m) == 'host' then
+    redis.call('HSET', KEYS[3], 'owner', m)
+    return ''
+  end
+end
+local heir = members[1] or redis.call('SRANDMEMBER', KEYS[1])
+if not heir then
+  redis.call('HDEL', KEYS[3], 'owner')
+  return ''
+end
+redis.call('HSET', KEYS[3], 'owner', heir)
+redis.call('HSET', KEYS[2], heir, 'host')
+return heir
+`)
+
+// RemoveClient takes the client out of its room and closes the room when it
+// was the last one. It returns the ID of the member promoted to host when the
+// owner left, or "".
+func (r *RedisRepo) RemoveClient(ctx context.Context, clientID string) (string, error) {
 	roomID, err := r.db.Get(ctx, r.keys.ClientKey(clientID)).Result()
 	if err == redis.Nil {
-		return ErrClientNotFound
+		return "", ErrClientNotFound
 	}
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	pipe := r.db.Pipeline()
-	pipe.SRem(ctx, r.keys.RoomClientsKey(roomID), clientID)
-	pipe.Del(ctx, r.keys.ClientKey(clientID))
-	pipe.Del(ctx, r.keys.ClientMetaKey(clientID))
-	count, _ := r.db.SCard(ctx, r.keys.RoomClientsKey(roomID)).Result()
-	if count == 0 {
-		pipe.Del(ctx, r.keys.RoomClientsKey(roomID))
-		pipe.Del(ctx, r.keys.RoomMetaKey(roomID))
-		pipe.SRem(ctx, r.keys.ActiveRoomsKey(), roomID)
+	keys := []string{
+		r.keys.RoomClientsKey(roomID),
+		r.keys.RoomRolesKey(roomID),
+		r.keys.RoomMetaKey(roomID),
+		r.keys.RoomJoinOrderKey(roomID),
+		r.keys.ClientKey(clientID),
+		r.keys.ClientMetaKey(clientID),
+		r.keys.PersistentRoomsKey(),
+		r.keys.RoomMemberRolesKey(roomID),
 	}
-	_, err = pipe.Exec(ctx)
-	return err
+	promoted, err := leaveScript.Run(ctx, r.db, keys, clientID, roomID).Text()
+	if err != nil {
+		return "", err
+	}
+	return promoted, r.closeIfEmpty(ctx, roomID)
 }
 
 func (r *RedisRepo) GetClientRoom(ctx context.Context, clientID string) (string, error) {
@@ -86,6 +125,7 @@ func (r *RedisRepo) GetClientInfo(ctx context.Context, clientID string) (*Client
 		RoomID:    roomID,
 		JoinedAt:  joinedTime,
 		UserAgent: meta["user_agent"],
+		Name:      meta["name"],
 	}, nil
 }
 func (r *RedisRepo) ClientExists(ctx context.Context, clientID string) (bool, error) {
@@ -120,8 +160,9 @@ func (r *RedisRepo) GetRoomStats(ctx context.Context, roomID string) (*RoomStats
 	}
 
 	stats := &RoomStats{
-		RoomID:  roomID,
-		Clients: count,
+		RoomInfo: roomInfoFromMeta(meta),
+		RoomID:   roomID,
+		Clients:  count,
 	}
 
 	if createdAt, ok := meta["created_at"]; ok {
@@ -130,6 +171,10 @@ func (r *RedisRepo) GetRoomStats(ctx context.Context, roomID string) (*RoomStats
 	if lastSeen, ok := meta["last_seen"]; ok {
 		stats.LastSeen = time.Unix(atol(lastSeen), 0)
 	}
+	stats.CallActive = meta["call_active"] == "1"
+	if stats.Lifecycle, err = r.GetRoomLifecycle(ctx, roomID); err != nil {
+		return nil, err
+	}
 
 	return stats, nil
 }
@@ -160,26 +205,77 @@ func (r *RedisRepo) SaveMessage(ctx context.Context, roomID string, msg *Message
 	if err != nil {
 		return err
 	}
+	lifecycle, err := r.GetRoomLifecycle(ctx, roomID)
+	if err != nil {
+		return err
+	}
 
 	pipe := r.db.Pipeline()
 	pipe.LPush(ctx, key, data)
-	pipe.LTrim(ctx, key, 0, 99)
-	pipe.Expire(ctx, key, 24*time.Hour)
+	if lifecycle == LifecycleEphemeral {
+		pipe.LTrim(ctx, key, 0, historyLimit-1)
+		pipe.Expire(ctx, key, historyTTL)
+	} else {
+		pipe.LTrim(ctx, key, 0, persistentHistoryLimit-1)
+	}
+	if maxLen := r.archiveQueue.Load(); maxLen > 0 {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.keys.ArchiveQueueKey(),
+			MaxLen: maxLen,
+			Approx: true,
+			Values: map[string]any{"message": data},
+		})
+	}
+	if maxLen := r.searchStream.Load(); maxLen > 0 {
+		pipe.XAdd(ctx, &redis.XAddArgs{
+			Stream: r.keys.SearchStreamKey(),
+			MaxLen: maxLen,
+			Approx: true,
+			Values: map[string]any{"message": data},
+		})
+	}
+	r.touchRoom(ctx, pipe, roomID)
 
 	_, err = pipe.Exec(ctx)
 	return err
 }
 
 func (r *RedisRepo) GetRecentMessages(ctx context.Context, roomID string, limit int64) ([]*Message, error) {
-	if limit <= 0 || limit > 100 {
+	if limit <= 0 || limit > historyPage {
 		limit = 50
 	}
+	return r.messageRange(ctx, roomID, 0, limit-1)
+}
+
+// GetMessagesBefore returns up to limit messages sent before the cutoff,
+// newest first. It pages through the whole Redis history, which persistent
+// rooms keep up to persistentHistoryLimit messages long.
+func (r *RedisRepo) GetMessagesBefore(ctx context.Context, roomID string, before time.Time, limit int64) ([]*Message, error) {
+	messages := make([]*Message, 0, limit)
+	for start := int64(0); ; start += historyPage {
+		page, err := r.messageRange(ctx, roomID, start, start+historyPage-1)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range page {
+			if int64(len(messages)) == limit {
+				return messages, nil
+			}
+			if msg.Timestamp.Before(before) {
+				messages = append(messages, msg)
+			}
+		}
+		if int64(len(page)) < historyPage {
+			return messages, nil
+		}
+	}
+}
 
-	data, err := r.db.LRange(ctx, r.keys.RoomMessagesKey(roomID), 0, limit-1).Result()
+func (r *RedisRepo) messageRange(ctx context.Context, roomID string, start, stop int64) ([]*Message, error) {
+	data, err := r.db.LRange(ctx, r.keys.RoomMessagesKey(roomID), start, stop).Result()
 	if err != nil {
 		return nil, err
 	}
-
 	messages := make([]*Message, 0, len(data))
 	for _, item := range data {
 		var msg Message
@@ -187,7 +283,6 @@ func (r *RedisRepo) GetRecentMessages(ctx context.Context, roomID string, limit
 			messages = append(messages, &msg)
 		}
 	}
-
 	return messages, nil
 }
 
@@ -196,6 +291,10 @@ func (r *RedisRepo) ClearRoom(ctx context.Context, roomID string) error {
 	if err != nil {
 		return err
 	}
+	listed, err := r.db.HGet(ctx, r.keys.RoomMetaKey(roomID), "directory_name").Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
 
 	pipe := r.db.Pipeline()
 	for _, clientID := range clients {
@@ -205,7 +304,17 @@ func (r *RedisRepo) ClearRoom(ctx context.Context, roomID string) error {
 	pipe.Del(ctx, r.keys.RoomClientsKey(roomID))
 	pipe.Del(ctx, r.keys.RoomMetaKey(roomID))
 	pipe.Del(ctx, r.keys.RoomMessagesKey(roomID))
+	pipe.Del(ctx, r.keys.RoomRolesKey(roomID))
+	pipe.Del(ctx, r.keys.RoomMemberRolesKey(roomID))
+	pipe.Del(ctx, r.keys.RoomJoinOrderKey(roomID))
+	pipe.Del(ctx, r.keys.RoomCallKey(roomID))
+	pipe.Del(ctx, r.keys.RoomRecordingsKey(roomID))
+	pipe.Del(ctx, r.keys.RoomLobbyKey(roomID))
+	pipe.Del(ctx, r.keys.RoomLobbyTicketsKey(roomID))
+	pipe.Del(ctx, r.keys.RoomLobbyDecisionsKey(roomID))
 	pipe.SRem(ctx, r.keys.ActiveRoomsKey(), roomID)
+	pipe.SRem(ctx, r.keys.PersistentRoomsKey(), roomID)
+	r.unlistRoom(ctx, pipe, roomID, listed)
 
 	_, err = pipe.Exec(ctx)
 	return err
@@ -0,0 +1,96 @@
+package redisrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestReturningMemberGetsKeptRoleOnlyInPersistentRooms(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+	join := func(room, id, member string) Role {
+		t.Helper()
+		if _, err := repo.JoinRoom(ctx, &ClientInfo{ID: id, Member: member, RoomID: room, Name: id, JoinedAt: time.Now()}, 0); err != nil {
+			t.Fatal(err)
+		}
+		role, err := repo.AssignRole(ctx, room, id, member)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return role
+	}
+
+	repo.SetRoomLifecycle(ctx, "standing", LifecyclePersistent)
+	for _, room := range []string{"standing", "pop-up"} {
+		join(room, room+"-host", "host-member")
+		join(room, room+"-guest", "guest-member")
+		join(room, room+"-lurker", "lurker-member")
+		if err := repo.SetRole(ctx, room, room+"-guest", RoleSpeaker); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{room + "-guest", room + "-lurker"} {
+			if _, err := repo.RemoveClient(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if n := db.HLen(ctx, repo.keys.RoomRolesKey("standing")).Val(); n != 1 {
+		t.Fatalf("persistent room holds %d roles for 1 member", n)
+	}
+	kept := db.HGetAll(ctx, repo.keys.RoomMemberRolesKey("standing")).Val()
+	if len(kept) != 1 || kept["guest-member"] != string(RoleSpeaker) {
+		t.Fatalf("kept roles %v, want only the guest's non-default role", kept)
+	}
+	if role := join("standing", "standing-guest-again", "guest-member"); role != RoleSpeaker {
+		t.Fatalf("returning member got %q, want the kept speaker role", role)
+	}
+	if role := join("standing", "standing-stranger", "other-member"); role != RoleParticipant {
+		t.Fatalf("new member got %q, want the default role", role)
+	}
+	if role := join("pop-up", "pop-up-guest-again", "guest-member"); role != RoleParticipant {
+		t.Fatalf("ephemeral room gave a returning member %q, want the default role", role)
+	}
+	if present, _ := repo.IsClientInRoom(ctx, "standing-guest", "standing"); present {
+		t.Fatal("member who left is still in the room")
+	}
+}
+
+func TestClearRoomLeavesNoRoomKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	repo.SetRoomLifecycle(ctx, "room-1", LifecyclePersistent)
+	for _, id := range []string{"host", "guest"} {
+		if _, err := repo.JoinRoom(ctx, &ClientInfo{ID: id, Member: id + "-member", RoomID: "room-1", JoinedAt: time.Now()}, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo.AssignRole(ctx, "room-1", "host", "host-member")
+	repo.DecideLobby(ctx, "room-1", "guest", true)
+	name, public := "Standing", true
+	repo.UpdateRoomInfo(ctx, "room-1", RoomInfoUpdate{Name: &name, Public: &public})
+	repo.SaveMessage(ctx, "room-1", &Message{Type: "chat", Content: "hi", Timestamp: time.Now()})
+	repo.SaveRecording(ctx, &Recording{EgressID: "EG_1", RoomID: "room-1", StartedAt: time.Now()})
+	repo.StartCall(ctx, "room-1")
+
+	if err := repo.ClearRoom(ctx, "room-1"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := db.Keys(ctx, "room:room-1*").Val(); len(keys) != 0 {
+		t.Fatalf("ClearRoom left %v", keys)
+	}
+	if n := db.ZCard(ctx, repo.keys.DirectoryNamesKey()).Val(); n != 0 {
+		t.Fatalf("ClearRoom left %d directory name entries", n)
+	}
+}
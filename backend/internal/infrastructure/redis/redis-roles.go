@@ -0,0 +1,110 @@
+package redisrepo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AssignRole gives the first member of a room the host role, a returning
+// member the role the room kept for it, and everyone else the room's
+// default role.
+func (r *RedisRepo) AssignRole(ctx context.Context, roomID, clientID, member string) (Role, error) {
+	isOwner, err := r.db.HSetNX(ctx, r.keys.RoomMetaKey(roomID), "owner", clientID).Result()
+	if err != nil {
+		return "", err
+	}
+	role := RoleHost
+	if !isOwner {
+		role, err = r.keptRole(ctx, roomID, member)
+		if err != nil {
+			return "", err
+		}
+	}
+	if role == "" {
+		settings, err := r.GetRoomSettings(ctx, roomID)
+		if err != nil {
+			return "", err
+		}
+		role = settings.DefaultRole
+	}
+	if err := r.SetRole(ctx, roomID, clientID, role); err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// keptRole returns the role the room kept for the member, or "".
+func (r *RedisRepo) keptRole(ctx context.Context, roomID, member string) (Role, error) {
+	if member == "" {
+		return "", nil
+	}
+	value, err := r.db.HGet(ctx, r.keys.RoomMemberRolesKey(roomID), member).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	role, _ := ParseRole(value)
+	return role, nil
+}
+
+func (r *RedisRepo) GetRole(ctx context.Context, roomID, clientID string) (Role, error) {
+	value, err := r.db.HGet(ctx, r.keys.RoomRolesKey(roomID), clientID).Result()
+	if err == redis.Nil {
+		return "", ErrClientNotInRoom
+	}
+	if err != nil {
+		return "", err
+	}
+	role, ok := ParseRole(value)
+	if !ok {
+		return "", ErrInvalidData
+	}
+	return role, nil
+}
+
+func (r *RedisRepo) SetRole(ctx context.Context, roomID, clientID string, role Role) error {
+	return r.db.HSet(ctx, r.keys.RoomRolesKey(roomID), clientID, string(role)).Err()
+}
+
+func (r *RedisRepo) GetRoomSettings(ctx context.Context, roomID string) (*RoomSettings, error) {
+	values, err := r.db.HMGet(ctx, r.keys.RoomMetaKey(roomID), "default_role", "max_members", "knock").Result()
+	if err != nil {
+		return nil, err
+	}
+	settings := &RoomSettings{DefaultRole: RoleParticipant}
+	if value, _ := values[0].(string); value != "" {
+		if role, ok := ParseRole(value); ok && role != RoleHost {
+			settings.DefaultRole = role
+		}
+	}
+	if value, ok := values[1].(string); ok {
+		settings.MaxMembers = parseMaxMembers(value)
+	}
+	settings.Knock = values[2] == "1"
+	return settings, nil
+}
+
+func (r *RedisRepo) UpdateRoomSettings(ctx context.Context, roomID string, settings *RoomSettings) error {
+	knock := "0"
+	if settings.Knock {
+		knock = "1"
+	}
+	return r.db.HSet(ctx, r.keys.RoomMetaKey(roomID), map[string]any{
+		"default_role": string(settings.DefaultRole),
+		"max_members":  settings.MaxMembers,
+		"knock":        knock,
+	}).Err()
+}
+
+func parseMaxMembers(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
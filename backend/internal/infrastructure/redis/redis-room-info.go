@@ -0,0 +1,106 @@
+package redisrepo
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// roomInfoRetries bounds how often UpdateRoomInfo retries when the room info
+// changed between its read and its write.
+const roomInfoRetries = 5
+
+var roomInfoFields = []string{"name", "topic", "description", "avatar_url", "public"}
+
+func (r *RedisRepo) GetRoomInfo(ctx context.Context, roomID string) (*RoomInfo, error) {
+	return r.roomInfo(ctx, r.db, roomID)
+}
+
+func (r *RedisRepo) roomInfo(ctx context.Context, db redis.Cmdable, roomID string) (*RoomInfo, error) {
+	values, err := db.HMGet(ctx, r.keys.RoomMetaKey(roomID), roomInfoFields...).Result()
+	if err != nil {
+		return nil, err
+	}
+	meta := make(map[string]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			meta[roomInfoFields[i]] = s
+		}
+	}
+	info := roomInfoFromMeta(meta)
+	return &info, nil
+}
+
+// UpdateRoomInfo writes the fields set in update, removing the cleared ones,
+// keeps the room directory in step and returns the resulting info. The room
+// meta is watched between reading the previous info and writing, so edits of
+// other fields are never overwritten and the directory never keeps a stale
+// name.
+func (r *RedisRepo) UpdateRoomInfo(ctx context.Context, roomID string, update RoomInfoUpdate) (*RoomInfo, error) {
+	key := r.keys.RoomMetaKey(roomID)
+	var info *RoomInfo
+	apply := func(tx *redis.Tx) error {
+		previous, err := r.roomInfo(ctx, tx, roomID)
+		if err != nil {
+			return err
+		}
+		next := *previous
+		update.Apply(&next)
+		set, unset := roomInfoChanges(update, &next)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(set) > 0 {
+				pipe.HSet(ctx, key, set)
+			}
+			if len(unset) > 0 {
+				pipe.HDel(ctx, key, unset...)
+			}
+			r.indexRoom(ctx, pipe, roomID, previous, &next)
+			return nil
+		})
+		info = &next
+		return err
+	}
+	var err error
+	for range roomInfoRetries {
+		if err = r.db.Watch(ctx, apply, key); err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// roomInfoChanges splits the fields set in update into the meta fields to
+// write and those to remove.
+func roomInfoChanges(update RoomInfoUpdate, info *RoomInfo) (map[string]any, []string) {
+	public := ""
+	if info.Public {
+		public = "1"
+	}
+	changed := []bool{update.Name != nil, update.Topic != nil, update.Description != nil, update.AvatarURL != nil, update.Public != nil}
+	values := []string{info.Name, info.Topic, info.Description, info.AvatarURL, public}
+	set := map[string]any{}
+	var unset []string
+	for i, field := range roomInfoFields {
+		switch {
+		case !changed[i]:
+		case values[i] == "":
+			unset = append(unset, field)
+		default:
+			set[field] = values[i]
+		}
+	}
+	return set, unset
+}
+
+func roomInfoFromMeta(meta map[string]string) RoomInfo {
+	return RoomInfo{
+		Name:        meta["name"],
+		Topic:       meta["topic"],
+		Description: meta["description"],
+		AvatarURL:   meta["avatar_url"],
+		Public:      meta["public"] == "1",
+	}
+}
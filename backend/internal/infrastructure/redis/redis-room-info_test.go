@@ -0,0 +1,49 @@
+package redisrepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestUpdateRoomInfoWritesOnlyChangedFields(t *testing.T) {
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer db.Close()
+	repo := NewRedisRepo(db)
+	ctx := context.Background()
+
+	name, topic, public := "Lobby", "welcome", true
+	if _, err := repo.UpdateRoomInfo(ctx, "room-1", RoomInfoUpdate{Name: &name, Public: &public}); err != nil {
+		t.Fatal(err)
+	}
+	// setting the topic keeps the fields written before
+	info, err := repo.UpdateRoomInfo(ctx, "room-1", RoomInfoUpdate{Topic: &topic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Lobby" || info.Topic != "welcome" || !info.Public {
+		t.Fatalf("unexpected info %+v", info)
+	}
+
+	cleared := ""
+	if _, err := repo.UpdateRoomInfo(ctx, "room-1", RoomInfoUpdate{Topic: &cleared}); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := repo.GetRoomInfo(ctx, "room-1")
+	if stored.Topic != "" || stored.Name != "Lobby" {
+		t.Fatalf("unexpected stored info %+v", stored)
+	}
+	if mr.HGet(repo.keys.RoomMetaKey("room-1"), "topic") != "" {
+		t.Fatal("cleared topic left in the room meta")
+	}
+
+	renamed := "Hall"
+	repo.UpdateRoomInfo(ctx, "room-1", RoomInfoUpdate{Name: &renamed})
+	names, _ := mr.ZMembers(repo.keys.DirectoryNamesKey())
+	if len(names) != 1 || names[0] != "hall\x00room-1" {
+		t.Fatalf("directory names %q, want only the new name", names)
+	}
+}
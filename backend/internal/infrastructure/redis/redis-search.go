@@ -0,0 +1,46 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// EnableSearchStream makes SaveMessage append every message to the search
+// stream, which keeps about maxLen of the latest messages for indexers.
+func (r *RedisRepo) EnableSearchStream(maxLen int64) {
+	r.searchStream.Store(maxLen)
+}
+
+// ReadSearchStream returns up to count messages queued after the cursor, an
+// empty cursor meaning the start of the stream, and the cursor to continue
+// from. Entries that do not decode are skipped.
+func (r *RedisRepo) ReadSearchStream(ctx context.Context, cursor string, count int) ([]*QueuedMessage, string, error) {
+	if cursor == "" {
+		cursor = "0"
+	}
+	streams, err := r.db.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{r.keys.SearchStreamKey(), cursor},
+		Count:   int64(count),
+		Block:   -1,
+	}).Result()
+	if err == redis.Nil {
+		return nil, cursor, nil
+	}
+	if err != nil {
+		return nil, cursor, err
+	}
+	var queued []*QueuedMessage
+	for _, stream := range streams {
+		for _, entry := range stream.Messages {
+			cursor = entry.ID
+			data, _ := entry.Values["message"].(string)
+			var msg Message
+			if err := json.Unmarshal([]byte(data), &msg); err == nil {
+				queued = append(queued, &QueuedMessage{ID: entry.ID, Message: &msg})
+			}
+		}
+	}
+	return queued, cursor, nil
+}
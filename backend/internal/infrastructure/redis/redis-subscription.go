@@ -0,0 +1,42 @@
+package redisrepo
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RoomSubscription is a single pub/sub connection whose set of room channels
+// grows and shrinks with the rooms that have members on this node.
+type RoomSubscription struct {
+	ps   *redis.PubSub
+	keys Keys
+}
+
+func (r *RedisRepo) NewRoomSubscription(ctx context.Context) *RoomSubscription {
+	return &RoomSubscription{
+		ps:   r.db.Subscribe(ctx),
+		keys: r.keys,
+	}
+}
+
+func (s *RoomSubscription) Join(ctx context.Context, roomID string) error {
+	return s.ps.Subscribe(ctx, s.keys.RoomChannel(roomID))
+}
+
+func (s *RoomSubscription) Leave(ctx context.Context, roomID string) error {
+	return s.ps.Unsubscribe(ctx, s.keys.RoomChannel(roomID))
+}
+
+func (s *RoomSubscription) Channel() <-chan *redis.Message {
+	return s.ps.Channel()
+}
+
+func (s *RoomSubscription) Close() error {
+	return s.ps.Close()
+}
+
+// Room returns the room a message received on the subscription was sent to.
+func (s *RoomSubscription) Room(msg *redis.Message) string {
+	return s.keys.ChannelRoom(msg.Channel)
+}
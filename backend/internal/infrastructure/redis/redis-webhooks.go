@@ -0,0 +1,185 @@
+package redisrepo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const deadLetterLimit = 1000
+
+func (r *RedisRepo) SaveWebhook(ctx context.Context, sub *WebhookSubscription) error {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return r.db.HSet(ctx, r.keys.WebhookSubscriptionsKey(), sub.ID, data).Err()
+}
+
+func (r *RedisRepo) DeleteWebhook(ctx context.Context, id string) error {
+	removed, err := r.db.HDel(ctx, r.keys.WebhookSubscriptionsKey(), id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *RedisRepo) GetWebhook(ctx context.Context, id string) (*WebhookSubscription, error) {
+	data, err := r.db.HGet(ctx, r.keys.WebhookSubscriptionsKey(), id).Result()
+	if err == redis.Nil {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sub WebhookSubscription
+	if err := json.Unmarshal([]byte(data), &sub); err != nil {
+		return nil, ErrInvalidData
+	}
+	return &sub, nil
+}
+
+// GetWebhooks returns all subscriptions, oldest first.
+func (r *RedisRepo) GetWebhooks(ctx context.Context) ([]*WebhookSubscription, error) {
+	data, err := r.db.HGetAll(ctx, r.keys.WebhookSubscriptionsKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]*WebhookSubscription, 0, len(data))
+	for _, item := range data {
+		var sub WebhookSubscription
+		if err := json.Unmarshal([]byte(item), &sub); err == nil {
+			subs = append(subs, &sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs, nil
+}
+
+// ScheduleDelivery stores the delivery and queues it for the given time.
+func (r *RedisRepo) ScheduleDelivery(ctx context.Context, d *WebhookDelivery, at time.Time) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	pipe := r.db.TxPipeline()
+	pipe.HSet(ctx, r.keys.WebhookDeliveriesKey(), d.ID, data)
+	pipe.ZAdd(ctx, r.keys.WebhookQueueKey(), redis.Z{Score: float64(at.UnixMilli()), Member: d.ID})
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// claimDeliveriesScript leases due deliveries by pushing their score past the
+// lease; queue entries whose delivery is gone are dropped.
+//
+// KEYS: webhook queue, webhook deliveries
+// ARGV: now in ms, lease end in ms, limit
+var claimDeliveriesScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+local claimed = {}
+for _, id in ipairs(ids) do
+  local data = redis.call('HGET', KEYS[2], id)
+  if data then
+    redis.call('ZADD', KEYS[1], 'XX', ARGV[2], id)
+    table.insert(claimed, data)
+  else
+    redis.call('ZREM', KEYS[1], id)
+  end
+end
+return claimed
+`)
+
+// ClaimDueDeliveries returns up to limit due deliveries and hides them from
+// other claims until lease has passed. They stay queued until completed,
+// rescheduled or dead-lettered, so a node that dies mid-delivery does not lose
+// them: they come due again when the lease runs out.
+func (r *RedisRepo) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int64) ([]*WebhookDelivery, error) {
+	keys := []string{r.keys.WebhookQueueKey(), r.keys.WebhookDeliveriesKey()}
+	data, err := claimDeliveriesScript.Run(ctx, r.db, keys, now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]*WebhookDelivery, 0, len(data))
+	for _, item := range data {
+		var d WebhookDelivery
+		if err := json.Unmarshal([]byte(item), &d); err == nil {
+			deliveries = append(deliveries, &d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *RedisRepo) CompleteDelivery(ctx context.Context, id string) error {
+	pipe := r.db.TxPipeline()
+	pipe.ZRem(ctx, r.keys.WebhookQueueKey(), id)
+	pipe.HDel(ctx, r.keys.WebhookDeliveriesKey(), id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeadLetterDelivery moves a delivery that ran out of attempts to the
+// dead-letter list, which keeps the most recent failures.
+func (r *RedisRepo) DeadLetterDelivery(ctx context.Context, d *WebhookDelivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	pipe := r.db.TxPipeline()
+	pipe.ZRem(ctx, r.keys.WebhookQueueKey(), d.ID)
+	pipe.HDel(ctx, r.keys.WebhookDeliveriesKey(), d.ID)
+	pipe.LPush(ctx, r.keys.WebhookDeadLetterKey(), data)
+	pipe.LTrim(ctx, r.keys.WebhookDeadLetterKey(), 0, deadLetterLimit-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetDeadLetters returns failed deliveries, newest first.
+func (r *RedisRepo) GetDeadLetters(ctx context.Context, limit int64) ([]*WebhookDelivery, error) {
+	if limit <= 0 || limit > deadLetterLimit {
+		limit = 100
+	}
+	data, err := r.db.LRange(ctx, r.keys.WebhookDeadLetterKey(), 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]*WebhookDelivery, 0, len(data))
+	for _, item := range data {
+		var d WebhookDelivery
+		if err := json.Unmarshal([]byte(item), &d); err == nil {
+			deliveries = append(deliveries, &d)
+		}
+	}
+	return deliveries, nil
+}
+
+// TakeDeadLetter removes a failed delivery from the dead-letter list so it can
+// be queued again.
+func (r *RedisRepo) TakeDeadLetter(ctx context.Context, id string) (*WebhookDelivery, error) {
+	data, err := r.db.LRange(ctx, r.keys.WebhookDeadLetterKey(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range data {
+		var d WebhookDelivery
+		if err := json.Unmarshal([]byte(item), &d); err != nil || d.ID != id {
+			continue
+		}
+		removed, err := r.db.LRem(ctx, r.keys.WebhookDeadLetterKey(), 1, item).Result()
+		if err != nil {
+			return nil, err
+		}
+		if removed == 0 {
+			break
+		}
+		return &d, nil
+	}
+	return nil, ErrDeliveryNotFound
+}
@@ -0,0 +1,209 @@
+package search
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	cursorKey   = "cursor"
+	mappingKey  = "mapping"
+	deleteBatch = 1000
+)
+
+// mappingVersion changes whenever newMapping does. An index on disk built
+// with another mapping is dropped on open and rebuilt from the search
+// stream, since a static mapping ignores fields it does not know.
+const mappingVersion = "2"
+
+var storedFields = []string{"room_id", "from", "name", "content", "timestamp"}
+
+// BleveIndex is the embedded index. It lives in a directory, or only in
+// memory when opened without a path.
+type BleveIndex struct {
+	idx bleve.Index
+}
+
+func OpenBleve(path string) (*BleveIndex, error) {
+	if path == "" {
+		idx, err := bleve.NewMemOnly(newMapping())
+		if err != nil {
+			return nil, err
+		}
+		return &BleveIndex{idx: idx}, nil
+	}
+	idx, err := bleve.Open(path)
+	if err == nil {
+		version, verr := idx.GetInternal([]byte(mappingKey))
+		if verr == nil && string(version) == mappingVersion {
+			return &BleveIndex{idx: idx}, nil
+		}
+		idx.Close()
+		if err := os.RemoveAll(path); err != nil {
+			return nil, err
+		}
+		err = bleve.ErrorIndexPathDoesNotExist
+	}
+	if err == bleve.ErrorIndexPathDoesNotExist {
+		idx, err = bleve.New(path, newMapping())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.SetInternal([]byte(mappingKey), []byte(mappingVersion)); err != nil {
+		idx.Close()
+		return nil, err
+	}
+	return &BleveIndex{idx: idx}, nil
+}
+
+func newMapping() mapping.IndexMapping {
+	keywordField := bleve.NewKeywordFieldMapping()
+	keywordField.Analyzer = keyword.Name
+	content := bleve.NewTextFieldMapping()
+	content.Analyzer = standard.Name
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("room_id", keywordField)
+	doc.AddFieldMappingsAt("from", keywordField)
+	doc.AddFieldMappingsAt("name", keywordField)
+	doc.AddFieldMappingsAt("sender", keywordField)
+	doc.AddFieldMappingsAt("content", content)
+	doc.AddFieldMappingsAt("timestamp", bleve.NewDateTimeFieldMapping())
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	return m
+}
+
+func (b *BleveIndex) Index(ctx context.Context, docs []*Document, cursor string) error {
+	batch := b.idx.NewBatch()
+	for _, doc := range docs {
+		// the timestamp goes in as a string so the stored value keeps its
+		// nanoseconds
+		err := batch.Index(doc.ID, map[string]any{
+			"room_id":   doc.RoomID,
+			"from":      doc.From,
+			"name":      doc.Name,
+			"sender":    strings.ToLower(doc.Name),
+			"content":   doc.Content,
+			"timestamp": doc.Timestamp.UTC().Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	batch.SetInternal([]byte(cursorKey), []byte(cursor))
+	return b.idx.Batch(batch)
+}
+
+func (b *BleveIndex) Cursor(ctx context.Context) (string, error) {
+	cursor, err := b.idx.GetInternal([]byte(cursorKey))
+	return string(cursor), err
+}
+
+func (b *BleveIndex) Search(ctx context.Context, q *Query) (*Results, error) {
+	var must []query.Query
+	words, phrases := ParseText(q.Text)
+	if len(words) > 0 {
+		match := bleve.NewMatchQuery(strings.Join(words, " "))
+		match.SetField("content")
+		match.SetOperator(query.MatchQueryOperatorAnd)
+		must = append(must, match)
+	}
+	for _, phrase := range phrases {
+		match := bleve.NewMatchPhraseQuery(phrase)
+		match.SetField("content")
+		must = append(must, match)
+	}
+	if len(must) == 0 && q.From == "" {
+		return nil, ErrEmptyQuery
+	}
+	if q.From != "" {
+		from := bleve.NewTermQuery(strings.ToLower(q.From))
+		from.SetField("sender")
+		must = append(must, from)
+	}
+	if len(q.Rooms) > 0 {
+		must = append(must, roomsQuery(q.Rooms))
+	}
+	if !q.After.IsZero() || !q.Before.IsZero() {
+		must = append(must, timeQuery(q.After, q.Before))
+	}
+
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(must...), q.Limit, q.Offset, false)
+	req.Fields = storedFields
+	if q.Newest {
+		req.SortBy([]string{"-timestamp", "-_score"})
+	}
+	res, err := b.idx.SearchInContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	results := &Results{Total: res.Total, Hits: make([]*Hit, 0, len(res.Hits))}
+	for _, h := range res.Hits {
+		hit := &Hit{Document: Document{ID: h.ID}, Score: h.Score}
+		hit.RoomID, _ = h.Fields["room_id"].(string)
+		hit.From, _ = h.Fields["from"].(string)
+		hit.Name, _ = h.Fields["name"].(string)
+		hit.Content, _ = h.Fields["content"].(string)
+		if ts, ok := h.Fields["timestamp"].(string); ok {
+			hit.Timestamp, _ = time.Parse(time.RFC3339Nano, ts)
+		}
+		results.Hits = append(results.Hits, hit)
+	}
+	return results, nil
+}
+
+func (b *BleveIndex) Delete(ctx context.Context, rooms, except []string, before time.Time) (int, error) {
+	q := bleve.NewBooleanQuery()
+	q.AddMust(timeQuery(time.Time{}, before))
+	if len(rooms) > 0 {
+		q.AddMust(roomsQuery(rooms))
+	} else if len(except) > 0 {
+		q.AddMustNot(roomsQuery(except))
+	}
+	deleted := 0
+	for {
+		res, err := b.idx.SearchInContext(ctx, bleve.NewSearchRequestOptions(q, deleteBatch, 0, false))
+		if err != nil || len(res.Hits) == 0 {
+			return deleted, err
+		}
+		batch := b.idx.NewBatch()
+		for _, h := range res.Hits {
+			batch.Delete(h.ID)
+		}
+		if err := b.idx.Batch(batch); err != nil {
+			return deleted, err
+		}
+		deleted += len(res.Hits)
+	}
+}
+
+func (b *BleveIndex) Close() error {
+	return b.idx.Close()
+}
+
+func roomsQuery(rooms []string) query.Query {
+	terms := make([]query.Query, len(rooms))
+	for i, room := range rooms {
+		term := bleve.NewTermQuery(room)
+		term.SetField("room_id")
+		terms[i] = term
+	}
+	return bleve.NewDisjunctionQuery(terms...)
+}
+
+func timeQuery(after, before time.Time) query.Query {
+	q := bleve.NewDateRangeQuery(after, before)
+	q.SetField("timestamp")
+	return q
+}
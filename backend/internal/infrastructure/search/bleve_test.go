@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSearchFromMatchesSenderName(t *testing.T) {
+	idx, err := OpenBleve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	ctx := context.Background()
+	now := time.Now()
+	docs := []*Document{
+		{ID: "1", RoomID: "room-1", From: "conn-a", Name: "Alice", Content: "the link is here", Timestamp: now},
+		{ID: "2", RoomID: "room-1", From: "conn-b", Name: "Alice", Content: "another link", Timestamp: now},
+		{ID: "3", RoomID: "room-1", From: "conn-c", Name: "Alicia", Content: "my link", Timestamp: now},
+	}
+	if err := idx.Index(ctx, docs, "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	// two connections of the same member, and not the name it prefixes
+	res, err := idx.Search(ctx, &Query{Text: "link", From: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != 2 {
+		t.Fatalf("found %d messages from alice, want 2", res.Total)
+	}
+	for _, hit := range res.Hits {
+		if hit.Name != "Alice" {
+			t.Fatalf("hit %+v, want only Alice's messages", hit.Document)
+		}
+	}
+}
+
+func TestOpenBleveRebuildsAnOutdatedIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	idx, err := OpenBleve(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	idx.Index(ctx, []*Document{{ID: "1", RoomID: "room-1", Content: "hello", Timestamp: time.Now()}}, "1")
+	idx.idx.SetInternal([]byte(mappingKey), []byte("1"))
+	idx.Close()
+
+	idx, err = OpenBleve(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if cursor, _ := idx.Cursor(ctx); cursor != "" {
+		t.Fatalf("outdated index kept cursor %q, want it rebuilt from the start", cursor)
+	}
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrEmptyQuery = errors.New("search needs words to look for or a sender")
+
+// Document is one indexed chat message. ID is its queue entry ID.
+type Document struct {
+	ID        string    `json:"id"`
+	RoomID    string    `json:"room_id"`
+	From      string    `json:"from"`
+	Name      string    `json:"name,omitempty"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Query finds messages containing every word and "quoted phrase" of Text.
+// Empty Rooms searches every room, so callers must scope it. From matches the
+// sender's display name, ignoring case; After and Before bound the timestamp
+// when set.
+type Query struct {
+	Text   string
+	Rooms  []string
+	From   string
+	After  time.Time
+	Before time.Time
+	// Newest sorts by time instead of relevance.
+	Newest bool
+	Offset int
+	Limit  int
+}
+
+type Hit struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+type Results struct {
+	Total uint64 `json:"total"`
+	Hits  []*Hit `json:"hits"`
+}
+
+// Index is a full-text index of chat messages. Index records the queue
+// position of the last document together with the batch, so an indexer can
+// resume where it stopped.
+type Index interface {
+	Index(ctx context.Context, docs []*Document, cursor string) error
+	Cursor(ctx context.Context) (string, error)
+	Search(ctx context.Context, q *Query) (*Results, error)
+	// Delete removes messages sent before the cutoff in the given rooms, or
+	// in every room but except when rooms is empty, and reports how many.
+	Delete(ctx context.Context, rooms, except []string, before time.Time) (int, error)
+	Close() error
+}
+
+// ParseText splits query text into words and double-quoted phrases. An
+// unterminated quote runs to the end of the text.
+func ParseText(text string) (words, phrases []string) {
+	for {
+		start := strings.IndexByte(text, '"')
+		if start < 0 {
+			break
+		}
+		words = append(words, strings.Fields(text[:start])...)
+		text = text[start+1:]
+		end := strings.IndexByte(text, '"')
+		if end < 0 {
+			end = len(text)
+		}
+		if phrase := strings.TrimSpace(text[:end]); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+		text = text[min(end+1, len(text)):]
+	}
+	return append(words, strings.Fields(text)...), phrases
+}
@@ -8,19 +8,31 @@ import (
 )
 
 func NewLogger(level string) *zap.Logger {
-	var levelLogging zapcore.Level
-	switch level {
-	case "info":
-		levelLogging = zapcore.InfoLevel
-	default:
-		levelLogging = zapcore.DebugLevel
-	}
+	return NewAtomicLogger(zap.NewAtomicLevelAt(ParseLevel(level)))
+}
+
+// NewAtomicLogger builds a logger whose level can be changed at runtime
+// through the given AtomicLevel.
+func NewAtomicLogger(level zap.AtomicLevel) *zap.Logger {
 	encoderCfg := zap.NewDevelopmentEncoderConfig()
 	encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	logger := zap.New(zapcore.NewCore(
 		zapcore.NewConsoleEncoder(encoderCfg),
 		zapcore.AddSync(os.Stdout),
-		levelLogging,
+		level,
 	))
 	return logger
 }
+
+func ParseLevel(level string) zapcore.Level {
+	switch level {
+	case "info":
+		return zapcore.InfoLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
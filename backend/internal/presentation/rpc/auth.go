@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// tokenAuth checks the bearer token of every call when one is configured.
+type tokenAuth struct {
+	token string
+}
+
+func (a tokenAuth) check(ctx context.Context) error {
+	if a.token == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		given, _ := strings.CutPrefix(value, "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(a.token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "missing or invalid token")
+}
+
+func (a tokenAuth) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.check(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a tokenAuth) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// TokenCredentials sends the token configured as grpc.token with every call,
+// e.g. grpc.WithPerRPCCredentials(rpc.TokenCredentials(token)).
+func TokenCredentials(token string) credentials.PerRPCCredentials {
+	return bearer(token)
+}
+
+type bearer string
+
+func (b bearer) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(b)}, nil
+}
+
+// RequireTransportSecurity is false because the API is commonly reached
+// over loopback or a private network without TLS.
+func (bearer) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Client calls the Caller service over an existing connection.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+func (c *Client) Connect(ctx context.Context) (grpc.BidiStreamingClient[ConnectRequest, Event], error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], "/"+serviceName+"/Connect", grpc.ForceCodec(jsonCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	return &grpc.GenericClientStream[ConnectRequest, Event]{ClientStream: stream}, nil
+}
+
+func (c *Client) RoomStats(ctx context.Context, in *RoomStatsRequest) (*RoomStatsResponse, error) {
+	out := new(RoomStatsResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/RoomStats", in, out, grpc.ForceCodec(jsonCodec{})); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) History(ctx context.Context, in *HistoryRequest) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/History", in, out, grpc.ForceCodec(jsonCodec{})); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
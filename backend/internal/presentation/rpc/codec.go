@@ -0,0 +1,25 @@
+package rpc
+
+import (
+	"encoding/json"
+)
+
+// CodecName is the gRPC content subtype of the Caller API. Messages are JSON
+// so the API needs no generated code. The codec is not registered globally:
+// the server forces it and Client passes it with every call, so other gRPC
+// users in the same binary keep the standard codecs.
+const CodecName = "json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return CodecName
+}
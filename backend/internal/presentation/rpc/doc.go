@@ -0,0 +1,21 @@
+// Package rpc serves the Caller API to backend services over gRPC.
+//
+// There is no .proto: the service is described by hand in service.go and its
+// messages are the JSON-tagged structs in messages.go. The wire contract is:
+//
+//   - Service caller.v1.Caller with the methods
+//     /caller.v1.Caller/Connect (bidirectional stream of ConnectRequest to Event),
+//     /caller.v1.Caller/RoomStats (RoomStatsRequest to RoomStatsResponse) and
+//     /caller.v1.Caller/History (HistoryRequest to HistoryResponse).
+//   - Every message is encoded as JSON, so calls use the content type
+//     application/grpc+json. Field names are the json tags of the structs.
+//   - When the server has a token, every call sends the metadata
+//     "authorization: Bearer <token>".
+//   - The first ConnectRequest carries join; it is answered with a welcome
+//     event whose memberToken can be sent back in join.member_token later.
+//   - Each later ConnectRequest carries message, which is either a JSON
+//     object shaped like a WebSocket frame ({"type":"history"},
+//     {"content":"hi"}) or a JSON string, which is sent to the room as plain
+//     chat text just like a text WebSocket frame.
+//   - Every Event holds in frame the JSON a WebSocket client would receive.
+package rpc
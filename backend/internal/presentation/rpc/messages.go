@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"encoding/json"
+	"time"
+)
+
+// ConnectRequest is one message on the Connect stream. The first one must
+// carry Join; after that each Message is handled like a WebSocket frame: a
+// JSON object is a request, a JSON string is plain chat text.
+type ConnectRequest struct {
+	Join    *JoinRequest    `json:"join,omitempty"`
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+// frame returns Message as the hub expects a WebSocket frame, with a JSON
+// string unwrapped to the text it holds.
+func (r *ConnectRequest) frame() []byte {
+	var text string
+	if len(r.Message) > 0 && r.Message[0] == '"' && json.Unmarshal(r.Message, &text) == nil {
+		return []byte(text)
+	}
+	return r.Message
+}
+
+type JoinRequest struct {
+	Room string `json:"room"`
+	Name string `json:"name"`
+	// MemberToken is the token of an earlier welcome; presenting it keeps
+	// the member's identity, and the roles persistent rooms kept for it.
+	MemberToken string `json:"member_token"`
+}
+
+// Event is a frame the hub delivered to the stream, in the same JSON shape
+// WebSocket clients receive.
+type Event struct {
+	Frame json.RawMessage `json:"frame"`
+}
+
+type RoomStatsRequest struct {
+	Room string `json:"room"`
+}
+
+type RoomStatsResponse struct {
+	Stats *redisrepo.RoomStats `json:"stats"`
+}
+
+type HistoryRequest struct {
+	Room   string    `json:"room"`
+	Limit  int64     `json:"limit"`
+	Before time.Time `json:"before"`
+}
+
+type HistoryResponse struct {
+	Messages []*redisrepo.Message `json:"messages"`
+}
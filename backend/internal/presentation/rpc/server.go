@@ -0,0 +1,171 @@
+package rpc
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/application/wire"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"net"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server exposes the hub to backend services over gRPC. Bots connected here
+// are ordinary hub clients and share rooms with WebSocket users.
+type Server struct {
+	hub    *hub.Hub
+	repo   *redisrepo.RedisRepo
+	grpc   *grpc.Server
+	Logger *zap.Logger
+}
+
+// NewServer builds the gRPC server. A non-empty token must be presented as a
+// bearer token by every call.
+func NewServer(h *hub.Hub, repo *redisrepo.RedisRepo, token string, lg *zap.Logger, opts ...grpc.ServerOption) *Server {
+	auth := tokenAuth{token: token}
+	opts = append([]grpc.ServerOption{
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	}, opts...)
+	s := &Server{
+		hub:    h,
+		repo:   repo,
+		grpc:   grpc.NewServer(opts...),
+		Logger: lg,
+	}
+	s.grpc.RegisterService(&serviceDesc, s)
+	return s
+}
+
+// Serve accepts connections on lis until Stop is called; any listener works,
+// including in-memory ones.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+func (s *Server) Stop() {
+	s.grpc.GracefulStop()
+}
+
+func (s *Server) Connect(stream grpc.BidiStreamingServer[ConnectRequest, Event]) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	if first.Join == nil || first.Join.Room == "" {
+		return status.Error(codes.InvalidArgument, "the first message must join a room")
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	clientID := uuid.New().String()
+	name := strings.TrimSpace(first.Join.Name)
+	if name == "" || len(name) > 64 {
+		name = clientID[:8]
+	}
+	member, token := client.MemberIdentity(first.Join.MemberToken)
+	cl := &client.Client{
+		ID:          clientID,
+		Name:        name,
+		Member:      member,
+		MemberToken: token,
+		Transport:   &streamTransport{stream: stream, cancel: cancel},
+		Format:      wire.JSON,
+		Send:        make(chan *wire.Frame, s.hub.SendQueueSize()),
+		Room:        first.Join.Room,
+		UserAgent:   "grpc",
+		Logger:      s.Logger,
+	}
+	s.hub.Register <- cl
+	defer s.hub.UnregisterClient(cl)
+	s.Logger.Info("Client with id is in room:", zap.String("id", cl.ID), zap.String("room_id", cl.Room), zap.String("transport", "grpc"))
+
+	welcome := wire.NewFrame(map[string]any{
+		"type":         "welcome",
+		"clientId":     cl.ID,
+		"memberToken":  cl.MemberToken,
+		"roomId":       cl.Room,
+		"capabilities": s.hub.Capabilities(),
+		"room":         s.hub.RoomInfo(cl.Room),
+	})
+	if err := cl.WriteFrame(welcome); err != nil {
+		return err
+	}
+
+	go func() {
+		defer cancel()
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			if len(req.Message) > 0 {
+				s.hub.HandleMessage(cl, req.frame())
+			}
+		}
+	}()
+
+	for {
+		select {
+		case frame, ok := <-cl.Send:
+			if !ok {
+				return nil
+			}
+			if err := cl.WriteFrame(frame); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (s *Server) RoomStats(ctx context.Context, in *RoomStatsRequest) (*RoomStatsResponse, error) {
+	if in.Room == "" {
+		return nil, status.Error(codes.InvalidArgument, "room is required")
+	}
+	stats, err := s.repo.GetRoomStats(ctx, in.Room)
+	if err != nil {
+		s.Logger.Error("Failed to load room stats", zap.String("room", in.Room), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load room stats")
+	}
+	return &RoomStatsResponse{Stats: stats}, nil
+}
+
+// History returns the most recent messages of a room, or those sent before
+// in.Before, oldest first.
+func (s *Server) History(ctx context.Context, in *HistoryRequest) (*HistoryResponse, error) {
+	if in.Room == "" {
+		return nil, status.Error(codes.InvalidArgument, "room is required")
+	}
+	messages, err := s.hub.History(ctx, in.Room, in.Before, in.Limit)
+	if err != nil {
+		s.Logger.Error("Failed to load history", zap.String("room", in.Room), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to load history")
+	}
+	return &HistoryResponse{Messages: messages}, nil
+}
+
+// streamTransport writes frames to a Connect stream. Only the Connect handler
+// goroutine writes; Close makes it return.
+type streamTransport struct {
+	stream grpc.BidiStreamingServer[ConnectRequest, Event]
+	cancel context.CancelFunc
+}
+
+func (t *streamTransport) Write(_ wire.Format, data []byte) error {
+	return t.stream.Send(&Event{Frame: data})
+}
+
+func (t *streamTransport) Close() error {
+	t.cancel()
+	return nil
+}
@@ -0,0 +1,208 @@
+package rpc
+
+import (
+	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "test-token"
+
+// newTestServer runs a hub on miniredis behind a gRPC server on an in-memory
+// listener and returns a dialer for it.
+func newTestServer(t *testing.T) func(opts ...grpc.DialOption) *Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { db.Close() })
+
+	repo := redisrepo.NewRedisRepo(db)
+	h := hub.NewHub(nil, config.HubConfig{SendQueueSize: 16, SlowConsumerPolicy: "drop_newest"}, config.LimitsConfig{Burst: 20}, nil, repo, zap.NewNop())
+	go h.Run()
+	t.Cleanup(h.Stop)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(h, repo, testToken, zap.NewNop())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return func(opts ...grpc.DialOption) *Client {
+		opts = append([]grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		}, opts...)
+		cc, err := grpc.NewClient("passthrough:///bufnet", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cc.Close() })
+		return NewClient(cc)
+	}
+}
+
+func authorized() grpc.DialOption {
+	return grpc.WithPerRPCCredentials(TokenCredentials(testToken))
+}
+
+// nextFrame reads events until one of the given type arrives.
+func nextFrame(t *testing.T, stream grpc.BidiStreamingClient[ConnectRequest, Event], frameType string) map[string]any {
+	t.Helper()
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", frameType, err)
+		}
+		var frame map[string]any
+		if err := json.Unmarshal(ev.Frame, &frame); err != nil {
+			t.Fatalf("decode frame %s: %v", ev.Frame, err)
+		}
+		if frame["type"] == frameType {
+			return frame
+		}
+	}
+}
+
+func TestConnectJoinsRoomAndRelaysChat(t *testing.T) {
+	dial := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := dial(authorized()).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&ConnectRequest{Join: &JoinRequest{Room: "room-1", Name: "bot"}}); err != nil {
+		t.Fatal(err)
+	}
+	welcome := nextFrame(t, stream, "welcome")
+	if welcome["roomId"] != "room-1" || welcome["clientId"] == "" {
+		t.Fatalf("unexpected welcome %v", welcome)
+	}
+
+	other, err := dial(authorized()).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Send(&ConnectRequest{Join: &JoinRequest{Room: "room-1", Name: "listener"}})
+	nextFrame(t, other, "welcome")
+
+	msg, _ := json.Marshal(map[string]string{"content": "hello over grpc"})
+	if err := stream.Send(&ConnectRequest{Message: msg}); err != nil {
+		t.Fatal(err)
+	}
+	chat := nextFrame(t, other, "chat")
+	if chat["content"] != "hello over grpc" || chat["from"] != welcome["clientId"] {
+		t.Fatalf("unexpected chat %v", chat)
+	}
+
+	// a JSON string is plain chat text, stored without its quotes
+	if err := stream.Send(&ConnectRequest{Message: json.RawMessage(`"plain \"text\""`)}); err != nil {
+		t.Fatal(err)
+	}
+	if chat := nextFrame(t, other, "chat"); chat["content"] != `plain "text"` {
+		t.Fatalf("unexpected chat %v", chat)
+	}
+}
+
+func TestConnectRequiresJoin(t *testing.T) {
+	dial := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := dial(authorized()).Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&ConnectRequest{Message: json.RawMessage(`{"content":"hi"}`)})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+}
+
+func TestRoomStatsAndHistory(t *testing.T) {
+	dial := newTestServer(t)
+	client := dial(authorized())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&ConnectRequest{Join: &JoinRequest{Room: "room-1", Name: "bot"}})
+	nextFrame(t, stream, "welcome")
+	// senders get no echo, so a second member confirms each message landed
+	listener, err := client.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener.Send(&ConnectRequest{Join: &JoinRequest{Room: "room-1", Name: "listener"}})
+	nextFrame(t, listener, "welcome")
+	for _, content := range []string{"first", "second", "third"} {
+		msg, _ := json.Marshal(map[string]string{"content": content})
+		stream.Send(&ConnectRequest{Message: msg})
+		nextFrame(t, listener, "chat")
+	}
+
+	stats, err := client.RoomStats(ctx, &RoomStatsRequest{Room: "room-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats == nil || stats.Stats.Clients != 2 {
+		t.Fatalf("unexpected stats %+v", stats.Stats)
+	}
+
+	// the hub saves a message just after publishing it
+	for {
+		history, err := client.History(ctx, &HistoryRequest{Room: "room-1", Limit: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history.Messages) == 2 && history.Messages[0].Content == "second" && history.Messages[1].Content == "third" {
+			break
+		}
+		if ctx.Err() != nil {
+			t.Fatalf("unexpected history %+v", history.Messages)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := client.History(ctx, &HistoryRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+}
+
+func TestCallsRequireToken(t *testing.T) {
+	dial := newTestServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := dial().RoomStats(ctx, &RoomStatsRequest{Room: "room-1"}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v, want Unauthenticated", err)
+	}
+	wrong := grpc.WithPerRPCCredentials(TokenCredentials("wrong"))
+	stream, err := dial(wrong).Connect(ctx)
+	if err == nil {
+		stream.Send(&ConnectRequest{Join: &JoinRequest{Room: "room-1"}})
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("got %v, want Unauthenticated", err)
+	}
+}
@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+const serviceName = "caller.v1.Caller"
+
+// CallerServer is implemented by Server; the interface exists so the service
+// description can be registered like generated gRPC code.
+type CallerServer interface {
+	Connect(grpc.BidiStreamingServer[ConnectRequest, Event]) error
+	RoomStats(context.Context, *RoomStatsRequest) (*RoomStatsResponse, error)
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*CallerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "RoomStats", Handler: roomStatsHandler},
+		{MethodName: "History", Handler: historyHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Connect",
+			Handler:       connectHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+func connectHandler(srv any, stream grpc.ServerStream) error {
+	return srv.(CallerServer).Connect(&grpc.GenericServerStream[ConnectRequest, Event]{ServerStream: stream})
+}
+
+func roomStatsHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(RoomStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallerServer).RoomStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/RoomStats"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(CallerServer).RoomStats(ctx, req.(*RoomStatsRequest))
+	})
+}
+
+func historyHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CallerServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/History"}
+	return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+		return srv.(CallerServer).History(ctx, req.(*HistoryRequest))
+	})
+}
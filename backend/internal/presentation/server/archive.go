@@ -0,0 +1,104 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/archiver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type retentionResponse struct {
+	RoomID           string `json:"room_id"`
+	Retention        string `json:"retention"`
+	RetentionSeconds int64  `json:"retention_seconds"`
+	Default          bool   `json:"default"`
+}
+
+// HistoryHandler returns a page of room history, oldest first, reaching into
+// the archive once the Redis history runs out. Query parameters: before
+// (RFC 3339 timestamp, usually the oldest one of the previous page) and
+// limit.
+func (s *WsServer) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var before time.Time
+	if raw := query.Get("before"); raw != "" {
+		t, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			http.Error(w, "before must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		before = t
+	}
+	limit, _ := strconv.ParseInt(query.Get("limit"), 10, 64)
+	roomID := r.PathValue("room")
+	messages, err := s.Hub.History(r.Context(), roomID, before, limit)
+	if err != nil {
+		s.Logger.Error("Failed to load history", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"room_id": roomID, "messages": messages})
+}
+
+func (s *WsServer) GetRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	s.writeRetention(w, r, r.PathValue("room"))
+}
+
+// SetRetentionHandler sets how long the room's messages are kept, as a Go
+// duration such as "720h"; "0s" keeps them forever.
+func (s *WsServer) SetRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Retention string `json:"retention"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	retention, err := time.ParseDuration(req.Retention)
+	if err != nil {
+		http.Error(w, "retention must be a duration such as 720h", http.StatusBadRequest)
+		return
+	}
+	roomID := r.PathValue("room")
+	err = s.Archive.SetRetention(r.Context(), roomID, retention)
+	if errors.Is(err, archiver.ErrInvalidRetention) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to set retention", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeRetention(w, r, roomID)
+}
+
+// ClearRetentionHandler returns the room to the default retention.
+func (s *WsServer) ClearRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("room")
+	if err := s.Archive.ClearRetention(r.Context(), roomID); err != nil {
+		s.Logger.Error("Failed to clear retention", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeRetention(w, r, roomID)
+}
+
+func (s *WsServer) writeRetention(w http.ResponseWriter, r *http.Request, roomID string) {
+	retention, own, err := s.Archive.Retention(r.Context(), roomID)
+	if err != nil {
+		s.Logger.Error("Failed to load retention", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, retentionResponse{
+		RoomID:           roomID,
+		Retention:        retention.String(),
+		RetentionSeconds: int64(retention.Seconds()),
+		Default:          !own,
+	})
+}
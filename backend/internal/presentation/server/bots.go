@@ -0,0 +1,82 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/bots"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type botRequest struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"`
+	Secret   string   `json:"secret"`
+	Commands []string `json:"commands"`
+	RoomID   string   `json:"room_id"`
+	// Replace must be set to overwrite a bot registered under the same name.
+	Replace bool `json:"replace"`
+}
+
+// RegisterBotHandler registers a bot for every room, or for the room in the
+// path for /rooms/{room}/bots. The secret is only returned here.
+func (s *WsServer) RegisterBotHandler(w http.ResponseWriter, r *http.Request) {
+	var req botRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if room := r.PathValue("room"); room != "" {
+		req.RoomID = room
+	}
+	bot := &redisrepo.Bot{
+		Name:     req.Name,
+		URL:      req.URL,
+		Secret:   req.Secret,
+		Commands: req.Commands,
+		RoomID:   req.RoomID,
+	}
+	err := s.Bots.Register(r.Context(), bot, req.Replace)
+	switch {
+	case errors.Is(err, bots.ErrCommandTaken) || errors.Is(err, bots.ErrReservedCommand) || errors.Is(err, redisrepo.ErrBotExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, bots.ErrInvalidName) || errors.Is(err, bots.ErrInvalidURL) || errors.Is(err, bots.ErrInvalidCommand):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		s.Logger.Error("Failed to register bot", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, bot)
+}
+
+func (s *WsServer) ListBotsHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := s.Bots.Bots(r.Context())
+	if err != nil {
+		s.Logger.Error("Failed to list bots", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, bot := range list {
+		bot.Secret = ""
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"bots": list})
+}
+
+func (s *WsServer) DeleteBotHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.Bots.Unregister(r.Context(), r.PathValue("name"))
+	if errors.Is(err, redisrepo.ErrBotNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to delete bot", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	livekitapi "JanArsMAI/Caller/internal/infrastructure/livekit"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+func (s *WsServer) LiveKitWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	cfg, _ := s.Hub.LiveKit()
+	if cfg == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	event, err := livekitapi.ReceiveWebhookEvent(r, cfg.ApiKey, cfg.ApiSecret)
+	if err != nil {
+		s.Logger.Warn("Rejected LiveKit webhook", zap.String("remote", r.RemoteAddr), zap.Error(err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if err := s.Hub.HandleLiveKitEvent(r.Context(), event); err != nil {
+		s.Logger.Error("Failed to handle LiveKit webhook", zap.String("event", event.GetEvent()), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
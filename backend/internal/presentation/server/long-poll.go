@@ -0,0 +1,81 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LongPollHandler is the last-resort transport. Without a session it joins
+// the room and returns the welcome frame with a session token; with one it
+// waits for frames and returns them as a JSON array.
+func (s *WsServer) LongPollHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("session")
+	if token == "" {
+		s.startPollSession(w, r)
+		return
+	}
+	sess, ok := s.sessions.get(token)
+	if !ok || sess.poll == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	if !sess.poll.Acquire() {
+		http.Error(w, "another poll is already waiting", http.StatusConflict)
+		return
+	}
+	defer sess.poll.Release()
+
+	c := sess.client
+	timer := time.NewTimer(pollTimeout)
+	defer timer.Stop()
+	select {
+	case frame, ok := <-c.Send:
+		if !ok {
+			http.Error(w, "session closed", http.StatusGone)
+			return
+		}
+		c.WriteFrame(frame)
+	drain:
+		for {
+			select {
+			case frame, ok := <-c.Send:
+				if !ok {
+					break drain
+				}
+				c.WriteFrame(frame)
+			default:
+				break drain
+			}
+		}
+	case <-timer.C:
+	case <-sess.poll.Done():
+		http.Error(w, "session closed", http.StatusGone)
+		return
+	case <-r.Context().Done():
+		return
+	}
+	s.writeBatch(w, sess.poll)
+}
+
+func (s *WsServer) startPollSession(w http.ResponseWriter, r *http.Request) {
+	transport := client.NewPollTransport()
+	c := s.newClient(r, transport, wire.JSON)
+	token := s.sessions.add(&session{client: c, poll: transport})
+	s.Hub.Register <- c
+	s.Logger.Info("Client with id is in room:", zap.String("id", c.ID), zap.String("room_id", c.Room), zap.String("transport", "long-poll"))
+	c.WriteFrame(s.welcome(c, map[string]any{"session": token}))
+	s.writeBatch(w, transport)
+}
+
+func (s *WsServer) writeBatch(w http.ResponseWriter, transport *client.PollTransport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(transport.Take()); err != nil {
+		s.Logger.Debug("Failed to write poll response", zap.Error(err))
+	}
+}
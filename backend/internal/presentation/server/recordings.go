@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// RecordingsHandler lists a room's finished recordings to the admin, or to a
+// member of the room identified by its session query parameter.
+func (s *WsServer) RecordingsHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.PathValue("room")
+	if memberRoom, ok := s.sessionRoom(w, r); !ok {
+		return
+	} else if memberRoom != "" && memberRoom != roomID {
+		http.Error(w, "not a member of this room", http.StatusForbidden)
+		return
+	}
+	recordings, err := s.Hub.GetCompletedRecordings(r.Context(), roomID)
+	if err != nil {
+		s.Logger.Error("Failed to list recordings", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{
+		"room_id":    roomID,
+		"recordings": recordings,
+	}); err != nil {
+		s.Logger.Error("Failed to write recordings", zap.Error(err))
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// RoomLifecycleHandler makes a room persistent or ephemeral. A persistent
+// room is created when it does not exist yet.
+func (s *WsServer) RoomLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Lifecycle string `json:"lifecycle"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	lifecycle, ok := redisrepo.ParseLifecycle(req.Lifecycle)
+	if !ok {
+		http.Error(w, "lifecycle must be ephemeral or persistent", http.StatusBadRequest)
+		return
+	}
+	roomID := r.PathValue("room")
+	if err := s.Hub.SetRoomLifecycle(r.Context(), roomID, lifecycle, ""); err != nil {
+		s.Logger.Error("Failed to set room lifecycle", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	stats, err := s.Hub.GetRoomStats(r.Context(), roomID)
+	if err != nil {
+		s.Logger.Error("Failed to load room stats", zap.String("room", roomID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, stats)
+}
+
+// RoomDirectoryHandler lists public rooms. Query parameters: q (name
+// prefix), sort (activity or name), offset and limit.
+func (s *WsServer) RoomDirectoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	page, err := s.Hub.ListRooms(r.Context(), redisrepo.DirectoryQuery{
+		Prefix: query.Get("q"),
+		Sort:   redisrepo.DirectorySort(query.Get("sort")),
+		Offset: offset,
+		Limit:  limit,
+	})
+	if err != nil {
+		s.Logger.Error("Failed to list rooms", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/infrastructure/search"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SearchHandler searches message history. Query parameters: q (words and
+// "quoted phrases"), room (repeatable), from (sender name), after and
+// before (RFC 3339), sort=newest, offset (up to 1000) and limit (up to 100). With a session token the
+// search is scoped like the search request of that session's client;
+// otherwise it needs the admin token and may cover every room.
+func (s *WsServer) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	memberRoom, ok := s.sessionRoom(w, r)
+	if !ok {
+		return
+	}
+	q := &search.Query{
+		Text:   query.Get("q"),
+		Rooms:  query["room"],
+		From:   query.Get("from"),
+		Newest: query.Get("sort") == "newest",
+	}
+	for name, t := range map[string]*time.Time{"after": &q.After, "before": &q.Before} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			http.Error(w, name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*t = parsed
+	}
+	q.Offset, _ = strconv.Atoi(query.Get("offset"))
+	q.Limit, _ = strconv.Atoi(query.Get("limit"))
+
+	res, err := s.Hub.Search(r.Context(), memberRoom, q)
+	switch {
+	case errors.Is(err, hub.ErrRoomNotSearchable):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, search.ErrEmptyQuery), errors.Is(err, hub.ErrSearchTooDeep):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		s.Logger.Error("Failed to search messages", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"query":   q.Text,
+		"total":   res.Total,
+		"offset":  q.Offset,
+		"results": res.Hits,
+	})
+}
@@ -1,47 +1,130 @@
 package server
 
 import (
+	"JanArsMAI/Caller/internal/application/archiver"
+	"JanArsMAI/Caller/internal/application/bots"
 	"JanArsMAI/Caller/internal/application/client"
 	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/application/indexer"
 	"JanArsMAI/Caller/internal/application/updater"
+	"JanArsMAI/Caller/internal/application/webhooks"
+	"JanArsMAI/Caller/internal/application/wire"
+	"JanArsMAI/Caller/internal/config"
 	"context"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
+var errUnsupportedSubprotocol = errors.New("none of the offered subprotocols is supported")
+
 type WsServer struct {
-	Updater *websocket.Upgrader
-	Hub     *hub.Hub
-	Mux     *http.ServeMux
-	Srv     *http.Server
-	Logger  *zap.Logger
+	Updater    *websocket.Upgrader
+	Origins    *updater.OriginGuard
+	Compressor *client.Compressor
+	Hub        *hub.Hub
+	Mux        *http.ServeMux
+	Srv        *http.Server
+	Logger     *zap.Logger
+	// Webhooks enables the webhook management endpoints when set.
+	Webhooks *webhooks.Dispatcher
+	// Bots enables the bot management endpoints when set.
+	Bots *bots.Registry
+	// Archive enables the retention endpoints when set.
+	Archive *archiver.Archiver
+	// Search enables the search endpoint when set.
+	Search *indexer.Indexer
+
+	adminToken atomic.Pointer[string]
+	sessions   *sessionStore
+	quit       chan struct{}
 }
 
-func NewWsServer(hub *hub.Hub, addr string, lg *zap.Logger) *WsServer {
+func NewWsServer(hub *hub.Hub, addr string, origins *updater.OriginPolicy, compression config.CompressionConfig, lg *zap.Logger) *WsServer {
 	mux := http.NewServeMux()
-	return &WsServer{
-		Updater: updater.NewUpdater(),
-		Hub:     hub,
-		Mux:     mux,
+	guard := updater.NewOriginGuard(origins)
+	compressor := client.NewCompressor(compression)
+	ws := &WsServer{
+		Updater:    updater.NewUpdater(guard, compressor, lg),
+		Origins:    guard,
+		Compressor: compressor,
+		Hub:        hub,
+		Mux:        mux,
 		Srv: &http.Server{
 			Addr:    addr,
 			Handler: mux,
 		},
-		Logger: lg,
+		Logger:   lg,
+		sessions: newSessionStore(),
+		quit:     make(chan struct{}),
 	}
+	ws.SetAdminToken("")
+	return ws
+}
+
+func (ws *WsServer) SetAdminToken(token string) {
+	ws.adminToken.Store(&token)
 }
 
 func (ws *WsServer) Start() error {
+	ln, err := net.Listen("tcp", ws.Srv.Addr)
+	if err != nil {
+		return err
+	}
+	return ws.Serve(ln)
+}
+
+// Serve runs the hub and serves HTTP on ln until Stop is called.
+func (ws *WsServer) Serve(ln net.Listener) error {
 	go ws.Hub.Run()
 	ws.Mux.HandleFunc("/", StaticHandler)
 	ws.Mux.HandleFunc("/ws", ws.WebSocketHandler)
-	return ws.Srv.ListenAndServe()
+	ws.Mux.HandleFunc("/stats/hub", ws.HubStatsHandler)
+	ws.Mux.HandleFunc("GET /capabilities", ws.CapabilitiesHandler)
+	ws.Mux.HandleFunc("/livekit/webhook", ws.LiveKitWebhookHandler)
+	ws.Mux.HandleFunc("GET /rooms", ws.RoomDirectoryHandler)
+	ws.Mux.HandleFunc("GET /rooms/{room}/recordings", ws.RecordingsHandler)
+	ws.Mux.HandleFunc("PUT /rooms/{room}/lifecycle", ws.requireAdmin(ws.RoomLifecycleHandler))
+	ws.Mux.HandleFunc("GET /rooms/{room}/history", ws.requireAdmin(ws.HistoryHandler))
+	ws.Mux.HandleFunc("GET /sse", ws.checkOrigin(ws.SSEHandler))
+	ws.Mux.HandleFunc("GET /poll", ws.checkOrigin(ws.LongPollHandler))
+	ws.Mux.HandleFunc("POST /sessions/{session}/messages", ws.checkOrigin(ws.SessionMessageHandler))
+	if ws.Webhooks != nil {
+		ws.Mux.HandleFunc("POST /webhooks", ws.requireAdmin(ws.CreateWebhookHandler))
+		ws.Mux.HandleFunc("POST /rooms/{room}/webhooks", ws.requireAdmin(ws.CreateWebhookHandler))
+		ws.Mux.HandleFunc("GET /webhooks", ws.requireAdmin(ws.ListWebhooksHandler))
+		ws.Mux.HandleFunc("DELETE /webhooks/{id}", ws.requireAdmin(ws.DeleteWebhookHandler))
+		ws.Mux.HandleFunc("GET /webhooks/dead-letters", ws.requireAdmin(ws.DeadLettersHandler))
+		ws.Mux.HandleFunc("POST /webhooks/dead-letters/{id}/replay", ws.requireAdmin(ws.ReplayDeadLetterHandler))
+	}
+	if ws.Bots != nil {
+		ws.Mux.HandleFunc("POST /bots", ws.requireAdmin(ws.RegisterBotHandler))
+		ws.Mux.HandleFunc("POST /rooms/{room}/bots", ws.requireAdmin(ws.RegisterBotHandler))
+		ws.Mux.HandleFunc("GET /bots", ws.requireAdmin(ws.ListBotsHandler))
+		ws.Mux.HandleFunc("DELETE /bots/{name}", ws.requireAdmin(ws.DeleteBotHandler))
+	}
+	if ws.Archive != nil {
+		ws.Mux.HandleFunc("GET /rooms/{room}/retention", ws.requireAdmin(ws.GetRetentionHandler))
+		ws.Mux.HandleFunc("PUT /rooms/{room}/retention", ws.requireAdmin(ws.SetRetentionHandler))
+		ws.Mux.HandleFunc("DELETE /rooms/{room}/retention", ws.requireAdmin(ws.ClearRetentionHandler))
+	}
+	if ws.Search != nil {
+		ws.Mux.HandleFunc("GET /search", ws.SearchHandler)
+	}
+	go ws.expireSessions()
+	return ws.Srv.Serve(client.CountingListener(ln))
 }
 
 func (ws *WsServer) Stop(ctx context.Context) error {
+	close(ws.quit)
 	return ws.Srv.Shutdown(ctx)
 }
 
@@ -50,33 +133,112 @@ func StaticHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *WsServer) WebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	if !updater.NegotiableSubprotocol(r) {
+		s.Updater.Error(w, r, http.StatusBadRequest, errUnsupportedSubprotocol)
+		return
+	}
 	conn, err := s.Updater.Upgrade(w, r, nil)
 	if err != nil {
-		s.Logger.Error("Ошибка апгрейда WebSocket:", zap.Error(err))
+		s.Logger.Debug("Ошибка апгрейда WebSocket:", zap.Error(err))
 		return
 	}
+	transport := &client.WebSocketTransport{Conn: conn, Compression: s.Compressor.For(r)}
+	c := s.newClient(r, transport, wire.FormatFor(conn.Subprotocol()))
+	s.Hub.Register <- c
+	s.Logger.Info("Client with id is in room:", zap.String("id", c.ID), zap.String("room_id", c.Room), zap.String("format", c.Format.String()))
+	if err := c.WriteFrame(s.welcome(c, nil)); err != nil {
+		s.Logger.Error("Error to send welcome: %v", zap.Error(err))
+	}
+	go c.WritePump()
+	go transport.ReadPump(c, s.Hub.HandleMessage, s.Hub.UnregisterClient)
+}
+
+// newClient builds a client for the room and name given in the query string,
+// whatever transport it arrived on.
+func (s *WsServer) newClient(r *http.Request, transport client.Transport, format wire.Format) *client.Client {
 	roomID := r.URL.Query().Get("room")
 	if roomID == "" {
 		roomID = uuid.New().String()
 	}
-	c := &client.Client{
-		ID:        uuid.New().String(),
-		Conn:      conn,
-		Send:      make(chan []byte, 256),
-		Room:      roomID,
-		UserAgent: r.UserAgent(),
-		Logger:    s.Logger,
+	clientID := uuid.New().String()
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	if name == "" || len(name) > 64 {
+		name = clientID[:8]
 	}
-	s.Hub.Register <- c
-	s.Logger.Info("Client with id is in room:", zap.String("id", c.ID), zap.String("room_id", roomID))
+	member, token := client.MemberIdentity(r.URL.Query().Get("member"))
+	return &client.Client{
+		ID:          clientID,
+		Name:        name,
+		Member:      member,
+		MemberToken: token,
+		Transport:   transport,
+		Format:      format,
+		Send:        make(chan *wire.Frame, s.Hub.SendQueueSize()),
+		Room:        roomID,
+		UserAgent:   r.UserAgent(),
+		Logger:      s.Logger,
+	}
+}
+
+func (s *WsServer) welcome(c *client.Client, extra map[string]any) *wire.Frame {
 	welcomeMsg := map[string]any{
-		"type":     "welcome",
-		"clientId": c.ID,
-		"roomId":   roomID,
+		"type":         "welcome",
+		"clientId":     c.ID,
+		"memberToken":  c.MemberToken,
+		"roomId":       c.Room,
+		"capabilities": s.Hub.Capabilities(),
+		"room":         s.Hub.RoomInfo(c.Room),
 	}
-	if err := conn.WriteJSON(welcomeMsg); err != nil {
-		s.Logger.Error("Error to send welcome: %v", zap.Error(err))
+	for k, v := range extra {
+		welcomeMsg[k] = v
+	}
+	return wire.NewFrame(welcomeMsg)
+}
+
+func (s *WsServer) HubStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := map[string]any{
+		"local_clients":  s.Hub.LocalClientsCount(),
+		"slow_consumers": s.Hub.SlowConsumerStats(),
+		"compression":    s.Compressor.Stats(),
+		"memory":         readMemoryStats(),
+	}
+	if s.Archive != nil {
+		queued, err := s.Archive.QueueLength(r.Context())
+		if err != nil {
+			s.Logger.Error("Failed to read archive queue length", zap.Error(err))
+		} else {
+			stats["archive_queue"] = queued
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		s.Logger.Error("Failed to write hub stats", zap.Error(err))
+	}
+}
+
+type MemoryStats struct {
+	HeapAlloc  uint64 `json:"heap_alloc"`
+	HeapInuse  uint64 `json:"heap_inuse"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"num_gc"`
+	Goroutines int    `json:"goroutines"`
+}
+
+func readMemoryStats() MemoryStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return MemoryStats{
+		HeapAlloc:  m.HeapAlloc,
+		HeapInuse:  m.HeapInuse,
+		Sys:        m.Sys,
+		NumGC:      m.NumGC,
+		Goroutines: runtime.NumGoroutine(),
+	}
+}
+
+func (s *WsServer) CapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Hub.Capabilities()); err != nil {
+		s.Logger.Error("Failed to write capabilities", zap.Error(err))
 	}
-	go c.WritePump()
-	go c.ReadPump(s.Hub.BroadcastToRoom)
 }
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxUpstreamMessage = 64 << 10
+	pollTimeout        = 25 * time.Second
+	pollSessionTTL     = time.Minute
+	sseKeepAlive       = 20 * time.Second
+)
+
+// session ties an HTTP fallback client to the secret token it sends upstream
+// messages with. The client ID is visible to the room, so it cannot be used.
+type session struct {
+	client *client.Client
+	poll   *client.PollTransport
+}
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]*session)}
+}
+
+func (st *sessionStore) add(sess *session) string {
+	token := uuid.New().String()
+	st.mu.Lock()
+	st.sessions[token] = sess
+	st.mu.Unlock()
+	return token
+}
+
+func (st *sessionStore) get(token string) (*session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	sess, ok := st.sessions[token]
+	return sess, ok
+}
+
+func (st *sessionStore) remove(token string) {
+	st.mu.Lock()
+	delete(st.sessions, token)
+	st.mu.Unlock()
+}
+
+// expired removes and returns poll sessions that stopped polling or were
+// closed by the hub.
+func (st *sessionStore) expired(ttl time.Duration) []*session {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var out []*session
+	for token, sess := range st.sessions {
+		if sess.poll == nil {
+			continue
+		}
+		closed := false
+		select {
+		case <-sess.poll.Done():
+			closed = true
+		default:
+		}
+		if closed || sess.poll.Idle(ttl) {
+			delete(st.sessions, token)
+			out = append(out, sess)
+		}
+	}
+	return out
+}
+
+func (s *WsServer) expireSessions() {
+	ticker := time.NewTicker(pollSessionTTL / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, sess := range s.sessions.expired(pollSessionTTL) {
+				s.Logger.Info("Long-poll session expired", zap.String("id", sess.client.ID))
+				s.Hub.UnregisterClient(sess.client)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// SessionMessageHandler accepts upstream messages from SSE and long-poll
+// clients; the body is handled exactly like a WebSocket frame.
+func (s *WsServer) SessionMessageHandler(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.sessions.get(r.PathValue("session"))
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpstreamMessage))
+	if err != nil {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if sess.poll != nil {
+		sess.poll.Touch()
+	}
+	s.Hub.HandleMessage(sess.client, body)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sessionRoom authorizes a request made for a room member or the admin. With
+// a session query parameter it returns the room of that session's client;
+// without one the caller must present the admin token and gets "". It writes
+// the error response itself when it reports false.
+func (s *WsServer) sessionRoom(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := r.URL.Query().Get("session")
+	if token == "" {
+		if !s.isAdmin(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return "", false
+		}
+		return "", true
+	}
+	sess, ok := s.sessions.get(token)
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return "", false
+	}
+	return sess.client.Room, true
+}
+
+// checkOrigin applies the WebSocket origin policy to the SSE and long-poll
+// transports, so pages of other sites cannot open sessions either.
+func (s *WsServer) checkOrigin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.Origins.Allow(r) {
+			s.Logger.Warn("Session request rejected",
+				zap.String("origin", r.Header.Get("Origin")),
+				zap.String("remote", r.RemoteAddr),
+				zap.String("path", r.URL.Path))
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
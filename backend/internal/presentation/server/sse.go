@@ -0,0 +1,56 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/client"
+	"JanArsMAI/Caller/internal/application/wire"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SSEHandler streams room events as Server-Sent Events for clients that
+// cannot keep a WebSocket open. The welcome event carries the session token
+// for POST /sessions/{session}/messages.
+func (s *WsServer) SSEHandler(w http.ResponseWriter, r *http.Request) {
+	transport := client.NewSSETransport(w)
+	c := s.newClient(r, transport, wire.JSON)
+	token := s.sessions.add(&session{client: c})
+	defer func() {
+		s.sessions.remove(token)
+		s.Hub.UnregisterClient(c)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s.Hub.Register <- c
+	s.Logger.Info("Client with id is in room:", zap.String("id", c.ID), zap.String("room_id", c.Room), zap.String("transport", "sse"))
+	if err := c.WriteFrame(s.welcome(c, map[string]any{"session": token})); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case frame, ok := <-c.Send:
+			if !ok {
+				return
+			}
+			if err := c.WriteFrame(frame); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := transport.Ping(); err != nil {
+				return
+			}
+		case <-transport.Done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"JanArsMAI/Caller/internal/application/webhooks"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	RoomID string   `json:"room_id"`
+}
+
+// requireAdmin checks the bearer token against server.admin_token. Without a
+// configured token the admin endpoints refuse every request.
+func (s *WsServer) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *WsServer) isAdmin(r *http.Request) bool {
+	token := *s.adminToken.Load()
+	if token == "" {
+		return false
+	}
+	given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (s *WsServer) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.Logger.Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// CreateWebhookHandler subscribes to events of every room, or of the room in
+// the path for /rooms/{room}/webhooks. The secret is only returned here.
+func (s *WsServer) CreateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if room := r.PathValue("room"); room != "" {
+		req.RoomID = room
+	}
+	sub := &redisrepo.WebhookSubscription{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		RoomID: req.RoomID,
+	}
+	if err := s.Webhooks.Subscribe(r.Context(), sub); err != nil {
+		if errors.Is(err, webhooks.ErrInvalidURL) || errors.Is(err, webhooks.ErrUnknownEvent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.Logger.Error("Failed to save webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusCreated, sub)
+}
+
+func (s *WsServer) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	subs, err := s.Webhooks.Subscriptions(r.Context())
+	if err != nil {
+		s.Logger.Error("Failed to list webhooks", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"webhooks": subs})
+}
+
+func (s *WsServer) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	err := s.Webhooks.Unsubscribe(r.Context(), r.PathValue("id"))
+	if errors.Is(err, redisrepo.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to delete webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *WsServer) DeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	deliveries, err := s.Webhooks.DeadLetters(r.Context(), limit)
+	if err != nil {
+		s.Logger.Error("Failed to list dead letters", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"deliveries": deliveries})
+}
+
+func (s *WsServer) ReplayDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, err := s.Webhooks.Replay(r.Context(), r.PathValue("id"))
+	if errors.Is(err, redisrepo.ErrDeliveryNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.Logger.Error("Failed to replay webhook delivery", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,406 @@
+// Package caller is a Go client for the Caller chat server. It dials the
+// WebSocket endpoint, keeps the connection alive with reconnects and
+// dispatches server frames to typed callbacks.
+package caller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrClosed         = errors.New("caller: client closed")
+	ErrNotConnected   = errors.New("caller: not connected")
+	ErrNoWelcome      = errors.New("caller: server did not send a welcome frame")
+	ErrHistoryPending = errors.New("caller: a history request is already pending")
+)
+
+type Options struct {
+	// URL of the WebSocket endpoint, for example ws://localhost:8080/ws.
+	URL string
+	// Room to join; empty asks the server for a new room. After the first
+	// welcome the client rejoins the same room on reconnect.
+	Room string
+	Name string
+	// MemberToken resumes the member identity of an earlier session, so
+	// persistent rooms give back the role they kept for it. After the first
+	// welcome the client presents the server's token on reconnect.
+	MemberToken string
+
+	Dialer     *websocket.Dialer
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnError receives connection errors that the client recovers from.
+	OnError func(error)
+}
+
+type Client struct {
+	opts Options
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+
+	mu          sync.Mutex
+	welcome     Welcome
+	handlers    map[string][]func(Event)
+	historyWait chan []Message
+	closed      bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a client; call Connect to dial. Handlers may be registered
+// before or after connecting.
+func New(opts Options) *Client {
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		opts:     opts,
+		handlers: make(map[string][]func(Event)),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Dial creates a client and connects it.
+func Dial(ctx context.Context, opts Options) (*Client, error) {
+	c := New(opts)
+	if err := c.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Connect dials the server, waits for the welcome frame and starts reading in
+// the background. Later connection losses are retried with backoff until
+// Close is called.
+func (c *Client) Connect(ctx context.Context) error {
+	if err := c.dial(ctx); err != nil {
+		return err
+	}
+	go c.run()
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) error {
+	u, err := url.Parse(c.opts.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	c.mu.Lock()
+	room, member := c.welcome.RoomID, c.welcome.MemberToken
+	c.mu.Unlock()
+	if room == "" {
+		room = c.opts.Room
+	}
+	if member == "" {
+		member = c.opts.MemberToken
+	}
+	if room != "" {
+		q.Set("room", room)
+	}
+	if member != "" {
+		q.Set("member", member)
+	}
+	if c.opts.Name != "" {
+		q.Set("name", c.opts.Name)
+	}
+	u.RawQuery = q.Encode()
+
+	conn, _, err := c.opts.Dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	var welcome Welcome
+	var frame struct {
+		Type string `json:"type"`
+	}
+	_, data, err := conn.ReadMessage()
+	if err == nil {
+		err = json.Unmarshal(data, &frame)
+	}
+	if err == nil && frame.Type != TypeWelcome {
+		err = ErrNoWelcome
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &welcome)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClosed
+	}
+	c.welcome = welcome
+	c.mu.Unlock()
+	c.writeMu.Lock()
+	c.conn = conn
+	c.writeMu.Unlock()
+
+	c.dispatch(Event{Type: TypeWelcome, Raw: data})
+	return nil
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+	for {
+		c.writeMu.Lock()
+		conn := c.conn
+		c.writeMu.Unlock()
+		c.readLoop(conn)
+		if c.ctx.Err() != nil {
+			return
+		}
+		if !c.reconnect() {
+			return
+		}
+	}
+}
+
+func (c *Client) readLoop(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if c.ctx.Err() == nil {
+				c.reportError(err)
+			}
+			conn.Close()
+			c.writeMu.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			c.writeMu.Unlock()
+			return
+		}
+		var frame struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.reportError(err)
+			continue
+		}
+		c.dispatch(Event{Type: frame.Type, Raw: data})
+	}
+}
+
+func (c *Client) reconnect() bool {
+	backoff := c.opts.MinBackoff
+	for {
+		jitter := time.Duration(rand.Int64N(int64(backoff)/2 + 1))
+		select {
+		case <-time.After(backoff/2 + jitter):
+		case <-c.ctx.Done():
+			return false
+		}
+		err := c.dial(c.ctx)
+		if err == nil {
+			return true
+		}
+		if errors.Is(err, ErrClosed) || c.ctx.Err() != nil {
+			return false
+		}
+		c.reportError(err)
+		backoff = min(backoff*2, c.opts.MaxBackoff)
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
+
+func (c *Client) dispatch(ev Event) {
+	if ev.Type == TypeHistory {
+		var h historyFrame
+		if err := ev.Decode(&h); err == nil {
+			c.mu.Lock()
+			wait := c.historyWait
+			c.historyWait = nil
+			c.mu.Unlock()
+			if wait != nil {
+				wait <- h.Messages
+			}
+		}
+	}
+	c.mu.Lock()
+	handlers := make([]func(Event), 0, len(c.handlers[ev.Type])+len(c.handlers[""]))
+	handlers = append(handlers, c.handlers[ev.Type]...)
+	handlers = append(handlers, c.handlers[""]...)
+	c.mu.Unlock()
+	for _, h := range handlers {
+		h(ev)
+	}
+}
+
+// On registers a callback for frames of the given type; an empty type
+// receives every frame. Callbacks run on the read goroutine.
+func (c *Client) On(eventType string, fn func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[eventType] = append(c.handlers[eventType], fn)
+}
+
+// OnWelcome is called after every successful (re)connect.
+func (c *Client) OnWelcome(fn func(Welcome)) {
+	c.On(TypeWelcome, decoded(fn))
+}
+
+func (c *Client) OnChat(fn func(Message)) {
+	c.On(TypeChat, decoded(fn))
+}
+
+// OnRoomEvent registers a callback for system events such as
+// call.participant_joined or room.settings.
+func (c *Client) OnRoomEvent(eventType string, fn func(Message)) {
+	c.On(eventType, decoded(fn))
+}
+
+func (c *Client) OnLiveKitToken(fn func(LiveKitToken)) {
+	c.On(TypeLiveKitToken, decoded(fn))
+}
+
+func (c *Client) OnGap(fn func(Gap)) {
+	c.On(TypeGap, decoded(fn))
+}
+
+func (c *Client) OnServerError(fn func(*ServerError)) {
+	c.On(TypeError, func(ev Event) {
+		var e ServerError
+		if ev.Decode(&e) == nil {
+			fn(&e)
+		}
+	})
+}
+
+func decoded[T any](fn func(T)) func(Event) {
+	return func(ev Event) {
+		var v T
+		if ev.Decode(&v) == nil {
+			fn(v)
+		}
+	}
+}
+
+// Welcome returns the most recent welcome frame.
+func (c *Client) Welcome() Welcome {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.welcome
+}
+
+// SendJSON writes an arbitrary request frame.
+func (c *Client) SendJSON(v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.conn.WriteJSON(v)
+}
+
+// Send posts a chat message to the room.
+func (c *Client) Send(text string) error {
+	return c.SendJSON(map[string]string{"type": TypeChat, "message": text})
+}
+
+// History requests up to limit recent messages, oldest first. It waits for
+// the reply on the read goroutine, so do not call it from a callback directly.
+func (c *Client) History(ctx context.Context, limit int) ([]Message, error) {
+	return c.HistoryBefore(ctx, time.Time{}, limit)
+}
+
+// HistoryBefore requests up to limit messages sent before the given time,
+// oldest first; pass the oldest timestamp of a page to get the one before
+// it. Servers with an archive page back beyond their recent history.
+func (c *Client) HistoryBefore(ctx context.Context, before time.Time, limit int) ([]Message, error) {
+	wait := make(chan []Message, 1)
+	c.mu.Lock()
+	if c.historyWait != nil {
+		c.mu.Unlock()
+		return nil, ErrHistoryPending
+	}
+	c.historyWait = wait
+	c.mu.Unlock()
+	forget := func() {
+		c.mu.Lock()
+		if c.historyWait == wait {
+			c.historyWait = nil
+		}
+		c.mu.Unlock()
+	}
+
+	req := map[string]any{"type": TypeHistory, "limit": limit}
+	if !before.IsZero() {
+		req["before"] = before
+	}
+	if err := c.SendJSON(req); err != nil {
+		forget()
+		return nil, err
+	}
+	select {
+	case messages := <-wait:
+		return messages, nil
+	case <-ctx.Done():
+		forget()
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		forget()
+		return nil, ErrClosed
+	}
+}
+
+// Close disconnects and stops reconnecting.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	c.cancel()
+
+	c.writeMu.Lock()
+	conn := c.conn
+	c.writeMu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	err := conn.Close()
+	<-c.done
+	return err
+}
+
+// Done is closed when the client has stopped for good.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,218 @@
+package caller
+
+import (
+	"JanArsMAI/Caller/internal/application/hub"
+	"JanArsMAI/Caller/internal/application/updater"
+	"JanArsMAI/Caller/internal/config"
+	redisrepo "JanArsMAI/Caller/internal/infrastructure/redis"
+	"JanArsMAI/Caller/internal/presentation/server"
+	"context"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// dropListener remembers accepted connections so a test can cut them and
+// make clients reconnect.
+type dropListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *dropListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *dropListener) drop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// startServer runs a WsServer backed by miniredis on an httptest listener
+// and returns its WebSocket URL.
+func startServer(t *testing.T) (string, *dropListener) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	db := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { db.Close() })
+
+	repo := redisrepo.NewRedisRepo(db)
+	h := hub.NewHub(nil, config.HubConfig{SendQueueSize: 16, SlowConsumerPolicy: "drop_newest"}, config.LimitsConfig{Burst: 20}, nil, repo, zap.NewNop())
+	origins, err := updater.NewOriginPolicy(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := server.NewWsServer(h, "", origins, config.CompressionConfig{}, zap.NewNop())
+
+	ln := &dropListener{Listener: httptest.NewUnstartedServer(nil).Listener}
+	go ws.Serve(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		ws.Stop(ctx)
+		h.Stop()
+	})
+	return "ws://" + ln.Addr().String() + "/ws", ln
+}
+
+func dialTest(t *testing.T, url, room, name string) *Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, Options{URL: url, Room: room, Name: name, MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		var zero T
+		t.Fatal("timed out waiting for the server")
+		return zero
+	}
+}
+
+func TestWelcome(t *testing.T) {
+	url, _ := startServer(t)
+
+	c := dialTest(t, url, "", "alice")
+	welcome := c.Welcome()
+	if welcome.ClientID == "" || welcome.RoomID == "" || !welcome.Capabilities.Chat {
+		t.Fatalf("unexpected welcome %+v", welcome)
+	}
+
+	other := dialTest(t, url, welcome.RoomID, "bob")
+	if other.Welcome().RoomID != welcome.RoomID {
+		t.Fatalf("joined %s, want %s", other.Welcome().RoomID, welcome.RoomID)
+	}
+}
+
+func TestSendAndReceive(t *testing.T) {
+	url, _ := startServer(t)
+	alice := dialTest(t, url, "room-1", "alice")
+	bob := dialTest(t, url, "room-1", "bob")
+
+	chats := make(chan Message, 1)
+	bob.OnChat(func(m Message) { chats <- m })
+	if err := alice.Send("hello bob"); err != nil {
+		t.Fatal(err)
+	}
+	msg := receive(t, chats)
+	if msg.Content != "hello bob" || msg.From != alice.Welcome().ClientID || msg.RoomID != "room-1" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	url, _ := startServer(t)
+	alice := dialTest(t, url, "room-1", "alice")
+	bob := dialTest(t, url, "room-1", "bob")
+
+	chats := make(chan Message, 3)
+	bob.OnChat(func(m Message) { chats <- m })
+	for _, text := range []string{"one", "two", "three"} {
+		alice.Send(text)
+		receive(t, chats)
+	}
+
+	// the hub saves a message just after publishing it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got []string
+	for ctx.Err() == nil {
+		messages, err := bob.History(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = got[:0]
+		for _, m := range messages {
+			got = append(got, m.Content)
+		}
+		if strings.Join(got, ",") == "two,three" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("history %v, want the last two messages oldest first", got)
+}
+
+func TestReconnectRejoinsRoom(t *testing.T) {
+	url, ln := startServer(t)
+	errs := make(chan error, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	alice, err := Dial(ctx, Options{
+		URL:        url,
+		Name:       "alice",
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+		OnError:    func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+	room := alice.Welcome().RoomID
+	first := alice.Welcome().ClientID
+	member := alice.Welcome().MemberToken
+
+	welcomes := make(chan Welcome, 1)
+	alice.OnWelcome(func(w Welcome) { welcomes <- w })
+	ln.drop()
+	receive(t, errs)
+	again := receive(t, welcomes)
+	if again.RoomID != room {
+		t.Fatalf("rejoined %s, want %s", again.RoomID, room)
+	}
+	if again.ClientID == first {
+		t.Fatal("reconnect reused the old client ID")
+	}
+	if again.MemberToken == "" || again.MemberToken != member {
+		t.Fatalf("reconnect got member token %q, want the first one kept", again.MemberToken)
+	}
+
+	bob := dialTest(t, url, room, "bob")
+	chats := make(chan Message, 1)
+	bob.OnChat(func(m Message) { chats <- m })
+	if err := alice.Send("back again"); err != nil {
+		t.Fatal(err)
+	}
+	if msg := receive(t, chats); msg.Content != "back again" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestClosedClient(t *testing.T) {
+	url, _ := startServer(t)
+	c := dialTest(t, url, "room-1", "alice")
+	c.Close()
+	<-c.Done()
+	if err := c.Send("too late"); err != ErrClosed {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
@@ -0,0 +1,95 @@
+package caller
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types sent by the server.
+const (
+	TypeWelcome      = "welcome"
+	TypeChat         = "chat"
+	TypeHistory      = "history"
+	TypeLiveKitToken = "livekit-token"
+	TypeError        = "error"
+	TypeGap          = "gap"
+)
+
+// Event is any frame received from the server. Raw holds the whole frame so
+// callers can decode fields the SDK does not know about.
+type Event struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+// Decode unmarshals the frame into v.
+func (e Event) Decode(v any) error {
+	return json.Unmarshal(e.Raw, v)
+}
+
+type Capabilities struct {
+	Chat        bool `json:"chat"`
+	Video       bool `json:"video"`
+	Recording   bool `json:"recording"`
+	History     bool `json:"history"`
+	Attachments bool `json:"attachments"`
+}
+
+// RoomInfo is the descriptive metadata set by the room's host. It also
+// arrives in the Data of room.updated events under "room".
+type RoomInfo struct {
+	Name        string `json:"name,omitempty"`
+	Topic       string `json:"topic,omitempty"`
+	Description string `json:"description,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+type Welcome struct {
+	ClientID     string       `json:"clientId"`
+	MemberToken  string       `json:"memberToken"`
+	RoomID       string       `json:"roomId"`
+	Capabilities Capabilities `json:"capabilities"`
+	Room         RoomInfo     `json:"room"`
+}
+
+// Message is a chat message or a room event published by the server, such as
+// call.participant_joined; events carry their details in Data. Name is the
+// sender's display name when the message was sent.
+type Message struct {
+	Type      string         `json:"type"`
+	From      string         `json:"from"`
+	Name      string         `json:"name,omitempty"`
+	RoomID    string         `json:"room_id"`
+	Content   string         `json:"content"`
+	Data      map[string]any `json:"data,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+type LiveKitToken struct {
+	Token      string `json:"token"`
+	LiveKitURL string `json:"livekitUrl"`
+	Room       string `json:"room"`
+	Identity   string `json:"identity"`
+	Role       string `json:"role"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// Gap reports messages the server dropped because the client read too slowly.
+type Gap struct {
+	RoomID string `json:"room_id"`
+	Missed int    `json:"missed"`
+}
+
+// ServerError is an error frame sent in reply to a request.
+type ServerError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ServerError) Error() string {
+	return "caller: " + e.Code + ": " + e.Message
+}
+
+type historyFrame struct {
+	Messages []Message `json:"messages"`
+}